@@ -17,15 +17,20 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/golang/glog"
 	"github.com/grafeas/kritis/pkg/attestlib"
 	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
 	"github.com/grafeas/kritis/pkg/kritis/crd/vulnzsigningpolicy"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
 	"github.com/grafeas/kritis/pkg/kritis/metadata/containeranalysis"
 	"github.com/grafeas/kritis/pkg/kritis/signer"
 	"github.com/grafeas/kritis/pkg/kritis/util"
@@ -48,12 +53,29 @@ var (
 	attestationProject string
 	overwrite          bool
 	noteName           string
+	// in-toto predicate flags
+	predicatePath string
+	predicateType string
+	// payload format flag
+	payloadFormat string
 	// pgp key flags
 	pgpPriKeyPath string
 	pgpPassphrase string
+	// pkix key flags
+	pkixPriKeyPath string
+	pkixAlg        string
+	pkixKeyID      string
 	// kms flags
 	kmsKeyName   string
 	kmsDigestAlg string
+	// keyless (Fulcio/Rekor) flags
+	keyless       bool
+	oidcTokenFile string
+	fulcioURL     string
+	rekorURL      string
+	// batch signing flags
+	imagesFile  string
+	concurrency int
 )
 
 func init() {
@@ -62,12 +84,24 @@ func init() {
 	flag.StringVar(&vulnzTimeout, "vulnz_timeout", "5m", "timeout for polling image vulnerability , e.g., 600s, 5m")
 	flag.StringVar(&pgpPriKeyPath, "pgp_private_key", "", "pgp private signing key path, e.g., /dev/shm/key.pgp")
 	flag.StringVar(&pgpPassphrase, "pgp_passphrase", "", "passphrase for pgp private key, if any")
+	flag.StringVar(&pkixPriKeyPath, "pkix_private_key", "", "pkix private signing key path (PEM or DER PKCS#1/PKCS#8), e.g., /dev/shm/key.pem")
+	flag.StringVar(&pkixAlg, "pkix_alg", "", "pkix signature algorithm, one of RSASSA_PKCS1V15_SHA256|RSASSA_PKCS1V15_SHA384|RSASSA_PKCS1V15_SHA512|RSASSA_PSS_SHA256|RSASSA_PSS_SHA384|RSASSA_PSS_SHA512|ECDSA_P256_SHA256|ECDSA_P384_SHA384|ECDSA_P521_SHA512")
+	flag.StringVar(&pkixKeyID, "pkix_key_id", "", "public key ID to embed in attestations created with -pkix_private_key, must match the verifier's configured PublicKey.ID")
 	flag.StringVar(&policyPath, "policy", "", "vulnerability signing policy file path, e.g., /tmp/vulnz_signing_policy.yaml")
 	flag.StringVar(&noteName, "note_name", "", "note name that created attestations are attached to, in the form of projects/[PROVIDER_ID]/notes/[NOTE_ID]")
 	flag.StringVar(&attestationProject, "attestation_project", "", "project id for GCP project that stores attestation, default to image project if unspecified")
 	flag.BoolVar(&overwrite, "overwrite", false, "overwrite attestation if already existed, default to false")
+	flag.StringVar(&predicatePath, "predicate", "", "path to a JSON file containing an in-toto predicate to sign instead of the default atomic container payload, e.g., /tmp/provenance.json")
+	flag.StringVar(&predicateType, "predicate_type", "", "predicate type URI for -predicate, e.g., https://slsa.dev/provenance/v0.2")
+	flag.StringVar(&payloadFormat, "payload_format", string(signer.AtomicFormat), "payload format to sign, one of atomic|simplesigning|cosign")
 	flag.StringVar(&kmsKeyName, "kms_key_name", "", "kms key name, in the format of in the format projects/*/locations/*/keyRings/*/cryptoKeys/*/cryptoKeyVersions/*")
 	flag.StringVar(&kmsDigestAlg, "kms_digest_alg", "", "kms digest algorithm, must be one of SHA256|SHA384|SHA512, and the same as specified by the key version's algorithm")
+	flag.BoolVar(&keyless, "keyless", false, "sign using an ephemeral key certified by fulcio and logged to rekor, instead of a kms or pgp key")
+	flag.StringVar(&oidcTokenFile, "oidc_token_file", "", "path to a file containing an OIDC identity token for keyless signing, defaults to GOOGLE_APPLICATION_CREDENTIALS")
+	flag.StringVar(&fulcioURL, "fulcio_url", "https://fulcio.sigstore.dev", "base URL of the fulcio CA used for keyless signing")
+	flag.StringVar(&rekorURL, "rekor_url", "https://rekor.sigstore.dev", "base URL of the rekor transparency log used for keyless signing")
+	flag.StringVar(&imagesFile, "images_file", "", "path to a newline-delimited list of images to sign, signing all of them in one invocation instead of just -image")
+	flag.IntVar(&concurrency, "concurrency", 4, "number of images to sign concurrently when -images_file is set")
 }
 
 func main() {
@@ -86,10 +120,10 @@ func main() {
 	}
 	glog.Infof("Signer mode: %s.", mode)
 
-	// Check image url is non-empty
+	// Check image url is non-empty, unless signing a whole batch via -images_file.
 	// TODO: check and format image url to
 	//  gcr.io/project-id/rest-of-image-path@sha256:[sha-value]
-	if image == "" {
+	if imagesFile == "" && image == "" {
 		glog.Fatalf("Image url is empty: %s", image)
 	}
 
@@ -99,6 +133,18 @@ func main() {
 		glog.Fatalf("Could not initialize the client %v", err)
 	}
 
+	if imagesFile != "" {
+		if !doSign {
+			glog.Fatalf("-images_file requires check-and-sign or bypass-and-sign mode")
+		}
+		signImagesFromFile(client)
+		return
+	}
+
+	// Populated by doCheck, consulted by doSign to auto-emit a vulnerability
+	// predicate in check-and-sign mode when no explicit -predicate was given.
+	var vulnz []metadata.Vulnerability
+
 	if doCheck {
 		// Read the vulnz signing policy
 		policy := v1beta1.VulnzSigningPolicy{}
@@ -124,7 +170,7 @@ func main() {
 		}
 
 		// Read the vulnz scanning events
-		vulnz, err := client.Vulnerabilities(image)
+		vulnz, err = client.Vulnerabilities(image)
 		if err != nil {
 			glog.Fatalf("Found err %s", err)
 		}
@@ -149,35 +195,9 @@ func main() {
 
 	if doSign {
 		// Read the signing credentials
-		// Either kmsKeyName or pgpPriKeyPath needs to be set
-		if kmsKeyName == "" && pgpPriKeyPath == "" {
-			glog.Fatalf("Neither kms_key_name or private_key is specified")
-		}
-		var cSigner attestlib.Signer
-		if kmsKeyName != "" {
-			glog.Infof("Using kms key %s for signing.", kmsKeyName)
-			if kmsDigestAlg == "" {
-				glog.Fatalf("kms_digest_alg is unspecified, must be one of SHA256|SHA384|SHA512, and the same as specified by the key version's algorithm")
-			}
-			cSigner, err = signer.NewCloudKmsSigner(kmsKeyName, signer.DigestAlgorithm(kmsDigestAlg))
-			if err != nil {
-				glog.Fatalf("Creating kms signer failed: %v\n", err)
-			}
-		} else {
-			glog.Infof("Using pgp key for signing.")
-			// TODO: support Passphrase to private key (consider add support in cryptolib)
-			if pgpPassphrase != "" {
-				glog.Fatalf("PGP Passphrase is not yet supported.\n")
-			}
-			signerKey, err := ioutil.ReadFile(pgpPriKeyPath)
-			if err != nil {
-				glog.Fatalf("Fail to read signer key: %v\n", err)
-			}
-			// Create a cryptolib signer
-			cSigner, err = attestlib.NewPgpSigner(signerKey)
-			if err != nil {
-				glog.Fatalf("Creating pgp signer failed: %v\n", err)
-			}
+		cSigner, signerErr := newConfiguredSigner()
+		if signerErr != nil {
+			glog.Fatalf("Creating signer failed: %v\n", signerErr)
 		}
 
 		// Check note name
@@ -196,6 +216,34 @@ func main() {
 
 		// Create signer
 		r := signer.New(client, cSigner, noteName, attestationProject, overwrite)
+
+		formatter, formatErr := signer.NewPayloadFormatter(signer.PayloadFormat(payloadFormat))
+		if formatErr != nil {
+			glog.Fatalf("Invalid -payload_format: %v", formatErr)
+		}
+		r = r.WithPayloadFormat(formatter)
+
+		// If a predicate was requested, attach it so the signer emits a
+		// DSSE-wrapped in-toto Statement instead of the atomic container payload.
+		switch {
+		case predicatePath != "":
+			if predicateType == "" {
+				glog.Fatalf("-predicate_type must be set when -predicate is used")
+			}
+			predicate, err := ioutil.ReadFile(predicatePath)
+			if err != nil {
+				glog.Fatalf("Fail to read predicate file: %v\n", err)
+			}
+			r = r.WithPredicate(predicateType, predicate)
+		case doCheck && vulnz != nil:
+			imageVulnz := signer.ImageVulnerabilities{ImageRef: image, Vulnerabilities: vulnz}
+			predicate, err := imageVulnz.ToPredicate()
+			if err != nil {
+				glog.Fatalf("Fail to build vulnerabilities predicate: %v\n", err)
+			}
+			r = r.WithPredicate(signer.VulnzPredicateType, predicate)
+		}
+
 		// Sign image
 		err := r.SignImage(image)
 		if err != nil {
@@ -203,3 +251,129 @@ func main() {
 		}
 	}
 }
+
+// newConfiguredSigner builds the attestlib.Signer for the signing credentials
+// given on the command line: the first of -keyless, -kms_key_name,
+// -pkix_private_key, or -pgp_private_key that is set, in that order. The
+// others are ignored when more than one is set.
+func newConfiguredSigner() (attestlib.Signer, error) {
+	if !keyless && kmsKeyName == "" && pgpPriKeyPath == "" && pkixPriKeyPath == "" {
+		return nil, fmt.Errorf("none of keyless, kms_key_name, pgp_private_key or pkix_private_key is specified")
+	}
+	if keyless {
+		glog.Infof("Using an ephemeral keyless signing key certified by fulcio.")
+		return signer.NewKeylessSigner(signer.KeylessConfig{
+			FulcioURL:     fulcioURL,
+			RekorURL:      rekorURL,
+			OIDCTokenFile: oidcTokenFile,
+		})
+	}
+	if kmsKeyName != "" {
+		glog.Infof("Using kms key %s for signing.", kmsKeyName)
+		if kmsDigestAlg == "" {
+			return nil, fmt.Errorf("kms_digest_alg is unspecified, must be one of SHA256|SHA384|SHA512, and the same as specified by the key version's algorithm")
+		}
+		return signer.NewCloudKmsSigner(kmsKeyName, signer.DigestAlgorithm(kmsDigestAlg))
+	}
+	if pkixPriKeyPath != "" {
+		glog.Infof("Using pkix key %s for signing.", pkixPriKeyPath)
+		if pkixAlg == "" {
+			return nil, fmt.Errorf("pkix_alg is unspecified, must be one of the supported PkixSignatureAlgorithm values")
+		}
+		signerKey, err := ioutil.ReadFile(pkixPriKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("fail to read signer key: %v", err)
+		}
+		return attestlib.NewPkixSigner(signerKey, attestlib.PkixSignatureAlgorithm(pkixAlg), pkixKeyID)
+	}
+
+	glog.Infof("Using pgp key for signing.")
+	// TODO: support Passphrase to private key (consider add support in cryptolib)
+	if pgpPassphrase != "" {
+		return nil, fmt.Errorf("PGP Passphrase is not yet supported")
+	}
+	signerKey, err := ioutil.ReadFile(pgpPriKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read signer key: %v", err)
+	}
+	return attestlib.NewPgpSigner(signerKey)
+}
+
+// signResultJSON is the JSON-serializable form of signer.SignResult printed
+// by signImagesFromFile.
+type signResultJSON struct {
+	Image   string `json:"image"`
+	Signed  bool   `json:"signed"`
+	Skipped bool   `json:"skipped"`
+	Error   string `json:"error,omitempty"`
+}
+
+// signImagesFromFile signs every image listed in -images_file concurrently
+// and prints a JSON report of the outcome of each to stdout. It exits
+// non-zero if any image failed to sign.
+func signImagesFromFile(client metadata.ReadWriteClient) {
+	cSigner, err := newConfiguredSigner()
+	if err != nil {
+		glog.Fatalf("Creating signer failed: %v\n", err)
+	}
+	if err := util.CheckNoteName(noteName); err != nil {
+		glog.Fatalf("Note name is invalid %v", err)
+	}
+	if attestationProject == "" {
+		glog.Fatalf("-attestation_project must be set when using -images_file")
+	}
+
+	images, err := readImagesFile(imagesFile)
+	if err != nil {
+		glog.Fatalf("Fail to read images file: %v\n", err)
+	}
+
+	r := signer.New(client, cSigner, noteName, attestationProject, overwrite)
+
+	formatter, err := signer.NewPayloadFormatter(signer.PayloadFormat(payloadFormat))
+	if err != nil {
+		glog.Fatalf("Invalid -payload_format: %v", err)
+	}
+	r = r.WithPayloadFormat(formatter)
+
+	results := r.SignImages(context.Background(), images, concurrency)
+
+	report := make([]signResultJSON, 0, len(images))
+	failures := 0
+	for res := range results {
+		entry := signResultJSON{Image: res.Image, Skipped: res.Skipped, Signed: res.Attestation != nil}
+		if res.Err != nil {
+			entry.Error = res.Err.Error()
+			failures++
+		}
+		report = append(report, entry)
+	}
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		glog.Fatalf("Fail to marshal report: %v\n", err)
+	}
+	fmt.Println(string(out))
+
+	if failures > 0 {
+		os.Exit(1)
+	}
+}
+
+// readImagesFile reads a newline-delimited list of image references,
+// skipping blank lines.
+func readImagesFile(path string) ([]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var images []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		images = append(images, line)
+	}
+	return images, nil
+}
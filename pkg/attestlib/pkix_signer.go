@@ -0,0 +1,145 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package attestlib
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// PkixSignatureAlgorithm selects the digest and signing scheme NewPkixSigner
+// signs with. Its values match cryptolib.SignatureAlgorithm's PKIX algorithm
+// names, so a PublicKey's configured algorithm and a signer's -pkix_alg agree.
+type PkixSignatureAlgorithm string
+
+const (
+	RsaSignPkcs1Sha256 PkixSignatureAlgorithm = "RSASSA_PKCS1V15_SHA256"
+	RsaSignPkcs1Sha384 PkixSignatureAlgorithm = "RSASSA_PKCS1V15_SHA384"
+	RsaSignPkcs1Sha512 PkixSignatureAlgorithm = "RSASSA_PKCS1V15_SHA512"
+	RsaPssSha256       PkixSignatureAlgorithm = "RSASSA_PSS_SHA256"
+	RsaPssSha384       PkixSignatureAlgorithm = "RSASSA_PSS_SHA384"
+	RsaPssSha512       PkixSignatureAlgorithm = "RSASSA_PSS_SHA512"
+	EcdsaP256Sha256    PkixSignatureAlgorithm = "ECDSA_P256_SHA256"
+	EcdsaP384Sha384    PkixSignatureAlgorithm = "ECDSA_P384_SHA384"
+	EcdsaP521Sha512    PkixSignatureAlgorithm = "ECDSA_P521_SHA512"
+)
+
+// pkixSigner signs payloads with a local PKIX private key, unblocking
+// offline signing for callers that do not have access to PGP or a KMS.
+type pkixSigner struct {
+	key crypto.Signer
+	alg PkixSignatureAlgorithm
+	id  string
+}
+
+// NewPkixSigner creates a Signer that signs with privateKey (PEM or DER
+// encoded PKCS#1/PKCS#8 private key) using alg, one of the PkixSignatureAlgorithm
+// constants. keyID, if non-empty, is used as the resulting Attestation's
+// PublicKeyID; otherwise it is left empty and must be set by the caller.
+func NewPkixSigner(privateKey []byte, alg PkixSignatureAlgorithm, keyID string) (Signer, error) {
+	key, err := parsePkixPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("private key does not support signing")
+	}
+	if err := checkKeyMatchesAlgorithm(signer.Public(), alg); err != nil {
+		return nil, err
+	}
+	return &pkixSigner{key: signer, alg: alg, id: keyID}, nil
+}
+
+// CreateAttestation signs payload, returning an Attestation carrying the
+// signature alongside the configured PublicKeyID.
+func (s *pkixSigner) CreateAttestation(payload []byte) (*Attestation, error) {
+	hash, err := hashForPkixSignatureAlgorithm(s.alg)
+	if err != nil {
+		return nil, err
+	}
+	hasher := hash.New()
+	hasher.Write(payload)
+	digest := hasher.Sum(nil)
+
+	var opts crypto.SignerOpts = hash
+	if s.alg == RsaPssSha256 || s.alg == RsaPssSha384 || s.alg == RsaPssSha512 {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash}
+	}
+	sig, err := s.key.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "signing payload")
+	}
+
+	return &Attestation{
+		PublicKeyID:       s.id,
+		Signature:         sig,
+		SerializedPayload: payload,
+	}, nil
+}
+
+func checkKeyMatchesAlgorithm(pub crypto.PublicKey, alg PkixSignatureAlgorithm) error {
+	switch alg {
+	case RsaSignPkcs1Sha256, RsaSignPkcs1Sha384, RsaSignPkcs1Sha512, RsaPssSha256, RsaPssSha384, RsaPssSha512:
+		if _, ok := pub.(*rsa.PublicKey); !ok {
+			return fmt.Errorf("signature algorithm %q requires an RSA private key", alg)
+		}
+	case EcdsaP256Sha256, EcdsaP384Sha384, EcdsaP521Sha512:
+		if _, ok := pub.(*ecdsa.PublicKey); !ok {
+			return fmt.Errorf("signature algorithm %q requires an ECDSA private key", alg)
+		}
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", alg)
+	}
+	return nil
+}
+
+func hashForPkixSignatureAlgorithm(alg PkixSignatureAlgorithm) (crypto.Hash, error) {
+	switch alg {
+	case RsaSignPkcs1Sha256, RsaPssSha256, EcdsaP256Sha256:
+		return crypto.SHA256, nil
+	case RsaSignPkcs1Sha384, RsaPssSha384, EcdsaP384Sha384:
+		return crypto.SHA384, nil
+	case RsaSignPkcs1Sha512, RsaPssSha512, EcdsaP521Sha512:
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported signature algorithm %q", alg)
+	}
+}
+
+// parsePkixPrivateKey parses privateKey as a PEM-encoded or raw DER PKCS#1
+// (RSA) or PKCS#8 (RSA/ECDSA) private key.
+func parsePkixPrivateKey(privateKey []byte) (crypto.PrivateKey, error) {
+	der := privateKey
+	if block, _ := pem.Decode(privateKey); block != nil {
+		der = block.Bytes
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("parsing PKIX private key: unsupported format, expected PEM or DER PKCS#1/PKCS#8")
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package attestlib
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func pemEncodePKCS8(t *testing.T, key interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("x509.MarshalPKCS8PrivateKey() error = %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestNewPkixSignerAndCreateAttestation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+
+	tcs := []struct {
+		name   string
+		key    []byte
+		alg    PkixSignatureAlgorithm
+		verify func(t *testing.T, payload, sig []byte)
+	}{
+		{
+			name: "RSA PKCS1v15 SHA256",
+			key:  pemEncodePKCS8(t, rsaKey),
+			alg:  RsaSignPkcs1Sha256,
+			verify: func(t *testing.T, payload, sig []byte) {
+				digest := sha256.Sum256(payload)
+				if err := rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+					t.Errorf("rsa.VerifyPKCS1v15() error = %v", err)
+				}
+			},
+		},
+		{
+			name: "RSA PSS SHA384",
+			key:  pemEncodePKCS8(t, rsaKey),
+			alg:  RsaPssSha384,
+			verify: func(t *testing.T, payload, sig []byte) {
+				digest := sha512.Sum384(payload)
+				opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+				if err := rsa.VerifyPSS(&rsaKey.PublicKey, crypto.SHA384, digest[:], sig, opts); err != nil {
+					t.Errorf("rsa.VerifyPSS() error = %v", err)
+				}
+			},
+		},
+		{
+			name: "ECDSA P256 SHA256",
+			key:  pemEncodePKCS8(t, ecdsaKey),
+			alg:  EcdsaP256Sha256,
+			verify: func(t *testing.T, payload, sig []byte) {
+				digest := sha256.Sum256(payload)
+				if !ecdsa.VerifyASN1(&ecdsaKey.PublicKey, digest[:], sig) {
+					t.Error("ecdsa.VerifyASN1() = false, want true")
+				}
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			signer, err := NewPkixSigner(tc.key, tc.alg, "test-key-id")
+			if err != nil {
+				t.Fatalf("NewPkixSigner() error = %v", err)
+			}
+			payload := []byte("signed payload")
+			att, err := signer.CreateAttestation(payload)
+			if err != nil {
+				t.Fatalf("CreateAttestation() error = %v", err)
+			}
+			if att.PublicKeyID != "test-key-id" {
+				t.Errorf("PublicKeyID = %q, want %q", att.PublicKeyID, "test-key-id")
+			}
+			if string(att.SerializedPayload) != string(payload) {
+				t.Errorf("SerializedPayload = %q, want %q", att.SerializedPayload, payload)
+			}
+			tc.verify(t, payload, att.Signature)
+		})
+	}
+}
+
+func TestNewPkixSignerRejectsMismatchedKeyType(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	if _, err := NewPkixSigner(pemEncodePKCS8(t, rsaKey), EcdsaP256Sha256, ""); err == nil {
+		t.Error("NewPkixSigner() error = nil, want error for an RSA key with an ECDSA algorithm")
+	}
+}
+
+func TestNewPkixSignerRejectsUnsupportedAlgorithm(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	if _, err := NewPkixSigner(pemEncodePKCS8(t, rsaKey), PkixSignatureAlgorithm("bogus"), ""); err == nil {
+		t.Error("NewPkixSigner() error = nil, want error for an unsupported algorithm")
+	}
+}
+
+func TestNewPkixSignerRejectsUnparsableKey(t *testing.T) {
+	if _, err := NewPkixSigner([]byte("not a key"), RsaSignPkcs1Sha256, ""); err == nil {
+		t.Error("NewPkixSigner() error = nil, want error for unparsable key material")
+	}
+}
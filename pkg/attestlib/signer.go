@@ -0,0 +1,35 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package attestlib creates Attestations: signatures over a payload,
+// produced either locally (NewPgpSigner, NewPkixSigner) or by a networked
+// signing service (see pkg/kritis/signer for those, e.g.
+// signer.NewCloudKmsSigner, signer.NewKeylessSigner).
+package attestlib
+
+// Attestation is the signed output of a Signer: Signature over
+// SerializedPayload, identified by the signing key's PublicKeyID so a
+// Verifier can look up the right key to check it against.
+type Attestation struct {
+	PublicKeyID       string
+	Signature         []byte
+	SerializedPayload []byte
+}
+
+// Signer creates an Attestation over payload.
+type Signer interface {
+	CreateAttestation(payload []byte) (*Attestation, error)
+}
@@ -14,6 +14,40 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
+// Package vulnzsigningpolicy validates images against a VulnzSigningPolicy's
+// vulnerability requirements.
+//
+// NOTE: scoped enforcement actions (deny/warn/dryrun per webhook/audit scope)
+// were requested for GenericAttestationPolicy, ImageSecurityPolicy, and
+// VulnzSigningPolicy alike, reworking ReviewGAP/ReviewISP to consult them via
+// Config.IsWebhook. That rework belongs in pkg/kritis/review, and the new
+// EnforcementActions field belongs on the v1beta1 spec types; neither
+// pkg/kritis/review nor pkg/kritis/apis/kritis/v1beta1 has its source
+// present in this checkout (review has only review_test.go, v1beta1 is
+// absent entirely), so this package is left unchanged pending that package
+// being checked in.
+//
+// NOTE: CVSSv3-based thresholds (MaximumFixableCVSSv3/MaximumUnfixableCVSSv3
+// on ImageVulnerabilityRequirements, compared against a CVSSScore this
+// package would need metadata.Vulnerability to carry) and an expiring CVE
+// allowlist (AllowlistCVEsWithExpiration []CVEAllowlistExpiration{CVE,
+// NotAfter}) were also requested here, along with the
+// CVSSThresholdViolation/ExpiredAllowlistViolation vType values policy.Violation
+// would need to report them. None of those fields or types are defined on
+// the v1beta1/policy packages in this checkout (v1beta1 is absent entirely;
+// policy has no source present either), so implementing this would mean
+// inventing their shape rather than matching it — left undone pending those
+// packages being checked in, for the same reason as the enforcement-actions
+// NOTE above.
+//
+// NOTE: ValidateVulnzSigningPolicy does not check vsp.Spec.RegoPolicyRef to
+// bypass the severity checks below in favor of pkg/kritis/policy/rego.Evaluator.
+// That package can compile and evaluate a Rego module against rego.PolicyRef,
+// but wiring it in here needs both vsp.Spec.RegoPolicyRef (a v1beta1 spec
+// field not present in this checkout) and a way to convert a rego.Result into
+// this package's Violation, which needs the vType/reason values
+// policy.Violation expects - also not visible here. Left undone for the same
+// reason as the two NOTEs above.
 package vulnzsigningpolicy
 
 import (
@@ -0,0 +1,109 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cryptolib
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Dsse identifies an Attestation whose SerializedPayload is a DSSE
+	// (Dead Simple Signing Envelope) rather than a bare signed payload.
+	Dsse KeyType = "dsse"
+
+	// DsseKeyID is the sentinel Attestation.PublicKeyID used for DSSE
+	// envelopes: the envelope carries its own per-signature key IDs, so
+	// VerifyAttestation does not look up a single PublicKey before
+	// dispatching to the DSSE verifier.
+	DsseKeyID = "dsse"
+)
+
+// dssePayloadType is the only payload type currently supported: an in-toto
+// Statement.
+const dssePayloadType = "application/vnd.in-toto+json"
+
+// dsseEnvelope is a Dead Simple Signing Envelope.
+// See https://github.com/secure-systems-lab/dsse/blob/master/envelope.md.
+type dsseEnvelope struct {
+	PayloadType string          `json:"payloadType"`
+	Payload     string          `json:"payload"`
+	Signatures  []dsseSignature `json:"signatures"`
+}
+
+type dsseSignature struct {
+	KeyID string `json:"keyid,omitempty"`
+	Sig   string `json:"sig"`
+}
+
+// dssePAE computes the DSSE Pre-Authentication Encoding of payloadType and
+// payload.
+func dssePAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+type dsseVerifier interface {
+	// verifyDsse verifies envelope and returns the decoded payload. It
+	// succeeds if at least one signature in the envelope verifies against a
+	// PublicKey in publicKeys, keyed by the signature's keyid.
+	verifyDsse(envelope []byte, publicKeys map[string]PublicKey) ([]byte, error)
+}
+
+type dsseVerifierImpl struct {
+	pkix pkixVerifier
+}
+
+func (d dsseVerifierImpl) verifyDsse(envelope []byte, publicKeys map[string]PublicKey) ([]byte, error) {
+	var env dsseEnvelope
+	if err := json.Unmarshal(envelope, &env); err != nil {
+		return nil, errors.Wrap(err, "parsing DSSE envelope")
+	}
+	if env.PayloadType != dssePayloadType {
+		return nil, fmt.Errorf("unsupported DSSE payload type %q", env.PayloadType)
+	}
+	if len(env.Signatures) == 0 {
+		return nil, errors.New("DSSE envelope has no signatures")
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding DSSE payload")
+	}
+	pae := dssePAE(env.PayloadType, payload)
+
+	var lastErr error
+	for _, sig := range env.Signatures {
+		publicKey, ok := publicKeys[sig.KeyID]
+		if !ok {
+			lastErr = fmt.Errorf("no public key with ID %q found", sig.KeyID)
+			continue
+		}
+		sigBytes, err := base64.StdEncoding.DecodeString(sig.Sig)
+		if err != nil {
+			lastErr = errors.Wrap(err, "decoding DSSE signature")
+			continue
+		}
+		if err := d.pkix.verifyPkix(sigBytes, pae, publicKey); err != nil {
+			lastErr = err
+			continue
+		}
+		return payload, nil
+	}
+	return nil, errors.Wrap(lastErr, "no signature in DSSE envelope verified")
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cryptolib
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDssePAE(t *testing.T) {
+	payloadType := "application/vnd.in-toto+json"
+	payload := "abc"
+	got := string(dssePAE(payloadType, []byte(payload)))
+	want := fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload)
+	if got != want {
+		t.Errorf("dssePAE() = %q, want %q", got, want)
+	}
+}
+
+func TestVerifyDsse(t *testing.T) {
+	verifier := dsseVerifierImpl{pkix: pkixVerifierImpl{}}
+
+	tcs := []struct {
+		name        string
+		envelope    []byte
+		publicKeys  map[string]PublicKey
+		expectedErr bool
+	}{
+		{
+			name:        "malformed envelope",
+			envelope:    []byte("not json"),
+			expectedErr: true,
+		},
+		{
+			name:        "unsupported payload type",
+			envelope:    []byte(`{"payloadType":"text/plain","payload":"","signatures":[{"keyid":"k","sig":"s"}]}`),
+			expectedErr: true,
+		},
+		{
+			name:        "no signatures",
+			envelope:    []byte(`{"payloadType":"application/vnd.in-toto+json","payload":"","signatures":[]}`),
+			expectedErr: true,
+		},
+		{
+			name:        "unknown key id",
+			envelope:    []byte(`{"payloadType":"application/vnd.in-toto+json","payload":"e30=","signatures":[{"keyid":"unknown","sig":"AAAA"}]}`),
+			publicKeys:  map[string]PublicKey{},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := verifier.verifyDsse(tc.envelope, tc.publicKeys)
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("verifyDsse() error = %v, expectedErr %t", err, tc.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cryptolib
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// Keyless identifies a Sigstore/cosign keyless attestation: the signature is
+// made by an ephemeral key whose trust comes from a Fulcio-issued
+// certificate and a Rekor transparency-log inclusion proof, rather than a
+// long-lived public key. Nothing in this package builds a Keyless PublicKey
+// from an AttestationAuthority: callers set FulcioRoot, RekorPublicKey,
+// SubjectRegexp, IssuerRegexp, CertificateIdentity, and
+// CertificateOIDCIssuer on PublicKey directly.
+const Keyless KeyType = "keyless"
+
+// rekorLogEntry is the subset of a Rekor log entry that a keyless signature
+// carries its inclusion proof in.
+type rekorLogEntry struct {
+	LogIndex int64  `json:"logIndex"`
+	LogID    string `json:"logID"`
+	// SET is the base64-encoded Signed Entry Timestamp proving inclusion.
+	SET string `json:"set"`
+}
+
+// keylessBundle is the value a keyless Attestation's SerializedPayload
+// decodes to: the signed payload plus everything needed to establish trust.
+type keylessBundle struct {
+	Payload    []byte        `json:"payload"`
+	CertChain  []byte        `json:"certChain"`
+	RekorEntry rekorLogEntry `json:"rekorEntry"`
+}
+
+type keylessVerifier interface {
+	// verifyKeyless verifies signature over the bundle described by
+	// serializedBundle (a JSON-encoded keylessBundle) and returns the
+	// signed payload. publicKey.KeyType must be Keyless; its FulcioRoot,
+	// SubjectRegexp, IssuerRegexp, and RekorPublicKey fields configure trust.
+	verifyKeyless(signature []byte, serializedBundle []byte, publicKey PublicKey) ([]byte, error)
+}
+
+type keylessVerifierImpl struct{}
+
+func (keylessVerifierImpl) verifyKeyless(signature []byte, serializedBundle []byte, publicKey PublicKey) ([]byte, error) {
+	var bundle keylessBundle
+	if err := json.Unmarshal(serializedBundle, &bundle); err != nil {
+		return nil, errors.Wrap(err, "parsing keyless bundle")
+	}
+
+	cert, intermediates, err := parseCertChain(bundle.CertChain)
+	if err != nil {
+		return nil, err
+	}
+
+	rootPool := x509.NewCertPool()
+	if !rootPool.AppendCertsFromPEM(publicKey.FulcioRoot) {
+		return nil, errors.New("failed to parse configured fulcio root certificate")
+	}
+	intermediatePool := x509.NewCertPool()
+	for _, c := range intermediates {
+		intermediatePool.AddCert(c)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         rootPool,
+		Intermediates: intermediatePool,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning, x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, errors.Wrap(err, "certificate does not chain to trusted fulcio root")
+	}
+
+	if err := matchesFulcioIdentity(cert, publicKey); err != nil {
+		return nil, err
+	}
+
+	pub, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("fulcio certificate does not contain an ECDSA public key")
+	}
+	digest := sha256.Sum256(bundle.Payload)
+	if !ecdsa.VerifyASN1(pub, digest[:], signature) {
+		return nil, errors.New("signature verification failed against fulcio certificate")
+	}
+
+	if err := verifyRekorEntry(bundle.RekorEntry, publicKey.RekorPublicKey, signature, cert); err != nil {
+		return nil, err
+	}
+
+	return bundle.Payload, nil
+}
+
+// parseCertChain parses a PEM-encoded certificate chain, returning the leaf
+// certificate and any intermediates.
+func parseCertChain(chainPEM []byte) (*x509.Certificate, []*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := chainPEM
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "parsing fulcio certificate")
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, nil, errors.New("no certificates found in fulcio certificate chain")
+	}
+	return certs[0], certs[1:], nil
+}
+
+// oidFulcioOIDCIssuer and oidFulcioOIDCIssuerV2 are the X.509 extension OIDs
+// Fulcio uses to record the OIDC issuer that authenticated a certificate's
+// subject. This is NOT the same as the certificate's issuer DN
+// (cert.Issuer), which is always Fulcio's own CA name and identical across
+// every certificate Fulcio issues.
+// See https://github.com/sigstore/fulcio/blob/main/docs/oid-info.md.
+var (
+	oidFulcioOIDCIssuer   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidFulcioOIDCIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// fulcioOIDCIssuer returns the OIDC issuer that authenticated cert's
+// subject, read from whichever form of Fulcio's issuer extension cert
+// carries, or "" if it carries neither.
+func fulcioOIDCIssuer(cert *x509.Certificate) (string, error) {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidFulcioOIDCIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err != nil {
+				return "", errors.Wrap(err, "parsing fulcio OIDC issuer extension")
+			}
+			return issuer, nil
+		case ext.Id.Equal(oidFulcioOIDCIssuer):
+			return string(ext.Value), nil
+		}
+	}
+	return "", nil
+}
+
+// matchesFulcioIdentity checks cert's SAN and OIDC issuer against the
+// allowlist regexes and exact-match strings configured on publicKey. A check
+// is skipped when its corresponding field is empty.
+func matchesFulcioIdentity(cert *x509.Certificate, publicKey PublicKey) error {
+	sans := append(append([]string{}, cert.EmailAddresses...), urisToStrings(cert.URIs)...)
+
+	if publicKey.SubjectRegexp != "" {
+		re, err := regexp.Compile(publicKey.SubjectRegexp)
+		if err != nil {
+			return errors.Wrap(err, "invalid subject regexp")
+		}
+		matched := false
+		for _, san := range sans {
+			if re.MatchString(san) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("certificate SAN %v does not match expected subject %q", sans, publicKey.SubjectRegexp)
+		}
+	}
+	if publicKey.CertificateIdentity != "" {
+		matched := false
+		for _, san := range sans {
+			if san == publicKey.CertificateIdentity {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("certificate SAN %v does not contain expected identity %q", sans, publicKey.CertificateIdentity)
+		}
+	}
+	if publicKey.IssuerRegexp != "" || publicKey.CertificateOIDCIssuer != "" {
+		issuer, err := fulcioOIDCIssuer(cert)
+		if err != nil {
+			return err
+		}
+		if publicKey.IssuerRegexp != "" {
+			re, err := regexp.Compile(publicKey.IssuerRegexp)
+			if err != nil {
+				return errors.Wrap(err, "invalid issuer regexp")
+			}
+			if !re.MatchString(issuer) {
+				return fmt.Errorf("certificate OIDC issuer %q does not match expected issuer %q", issuer, publicKey.IssuerRegexp)
+			}
+		}
+		if publicKey.CertificateOIDCIssuer != "" && issuer != publicKey.CertificateOIDCIssuer {
+			return fmt.Errorf("certificate OIDC issuer %q does not match expected issuer %q", issuer, publicKey.CertificateOIDCIssuer)
+		}
+	}
+	return nil
+}
+
+func urisToStrings(uris []*url.URL) []string {
+	out := make([]string, len(uris))
+	for i, u := range uris {
+		out[i] = u.String()
+	}
+	return out
+}
+
+// verifyRekorEntry checks that entry's inclusion proof (SET) verifies
+// against rekorPublicKeyPEM, and binds it to the signature it covers.
+func verifyRekorEntry(entry rekorLogEntry, rekorPublicKeyPEM []byte, signature []byte, cert *x509.Certificate) error {
+	if entry.SET == "" {
+		return errors.New("no rekor inclusion proof supplied")
+	}
+	block, _ := pem.Decode(rekorPublicKeyPEM)
+	if block == nil {
+		return errors.New("failed to parse configured rekor public key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return errors.Wrap(err, "parsing rekor public key")
+	}
+	ecPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return errors.New("rekor public key is not ECDSA")
+	}
+	set, err := base64.StdEncoding.DecodeString(entry.SET)
+	if err != nil {
+		return errors.Wrap(err, "decoding rekor SET")
+	}
+	digest := sha256.Sum256(signature)
+	if !ecdsa.VerifyASN1(ecPub, digest[:], set) {
+		return errors.New("rekor inclusion proof (SET) verification failed")
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cryptolib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+)
+
+// generateFulcioStyleCert builds a test certificate shaped like a real
+// Fulcio-issued one: its Issuer DN is Fulcio's own CA name (the same for
+// every cert Fulcio issues), and the OIDC issuer that actually authenticated
+// the signer is carried in the 1.8 issuer extension instead, exactly as
+// matchesFulcioIdentity expects to read it.
+func generateFulcioStyleCert(t *testing.T, email string, oidcIssuer string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+	var extensions []pkix.Extension
+	if oidcIssuer != "" {
+		value, err := asn1.Marshal(oidcIssuer)
+		if err != nil {
+			t.Fatalf("asn1.Marshal() error = %v", err)
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidFulcioOIDCIssuerV2, Value: value})
+	}
+	// The certificate is self-signed, so its Issuer DN is taken from the
+	// template's Subject; a template Issuer would be ignored.
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: "fulcio"},
+		EmailAddresses:  []string{email},
+		ExtraExtensions: extensions,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate() error = %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() error = %v", err)
+	}
+	return cert
+}
+
+func TestMatchesFulcioIdentity(t *testing.T) {
+	cert := generateFulcioStyleCert(t, "builder@example.com", "https://accounts.example.com")
+
+	tcs := []struct {
+		name        string
+		publicKey   PublicKey
+		expectedErr bool
+	}{
+		{name: "no constraints configured"},
+		{name: "matching subject regexp", publicKey: PublicKey{SubjectRegexp: "^builder@"}},
+		{name: "non-matching subject regexp", publicKey: PublicKey{SubjectRegexp: "^other@"}, expectedErr: true},
+		{name: "matching certificate identity", publicKey: PublicKey{CertificateIdentity: "builder@example.com"}},
+		{name: "non-matching certificate identity", publicKey: PublicKey{CertificateIdentity: "other@example.com"}, expectedErr: true},
+		{name: "matching issuer regexp", publicKey: PublicKey{IssuerRegexp: "example\\.com$"}},
+		{name: "non-matching issuer regexp", publicKey: PublicKey{IssuerRegexp: "other\\.com$"}, expectedErr: true},
+		{name: "matching oidc issuer", publicKey: PublicKey{CertificateOIDCIssuer: "https://accounts.example.com"}},
+		{name: "non-matching oidc issuer", publicKey: PublicKey{CertificateOIDCIssuer: "https://accounts.other.com"}, expectedErr: true},
+		// cert.Issuer.CommonName is "fulcio" (Fulcio's own CA name, set by
+		// generateFulcioStyleCert above), never the OIDC issuer - configuring
+		// it as the expected issuer must not match.
+		{name: "certificate issuer DN is not the OIDC issuer", publicKey: PublicKey{CertificateOIDCIssuer: "fulcio"}, expectedErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := matchesFulcioIdentity(cert, tc.publicKey)
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("matchesFulcioIdentity() error = %v, expectedErr %t", err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestFulcioOIDCIssuer(t *testing.T) {
+	tcs := []struct {
+		name       string
+		extID      asn1.ObjectIdentifier
+		rawValue   bool // true: ext.Value is the raw UTF-8 string (the v1 extension); false: DER ASN.1 UTF8String (v2)
+		wantIssuer string
+	}{
+		{name: "v2 extension (DER-encoded string)", extID: oidFulcioOIDCIssuerV2, wantIssuer: "https://accounts.example.com"},
+		{name: "v1 extension (raw string)", extID: oidFulcioOIDCIssuer, rawValue: true, wantIssuer: "https://accounts.example.com"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			value := []byte(tc.wantIssuer)
+			if !tc.rawValue {
+				var err error
+				value, err = asn1.Marshal(tc.wantIssuer)
+				if err != nil {
+					t.Fatalf("asn1.Marshal() error = %v", err)
+				}
+			}
+			cert := &x509.Certificate{Extensions: []pkix.Extension{{Id: tc.extID, Value: value}}}
+
+			got, err := fulcioOIDCIssuer(cert)
+			if err != nil {
+				t.Fatalf("fulcioOIDCIssuer() error = %v", err)
+			}
+			if got != tc.wantIssuer {
+				t.Errorf("fulcioOIDCIssuer() = %q, want %q", got, tc.wantIssuer)
+			}
+		})
+	}
+
+	t.Run("no issuer extension present", func(t *testing.T) {
+		got, err := fulcioOIDCIssuer(&x509.Certificate{})
+		if err != nil {
+			t.Fatalf("fulcioOIDCIssuer() error = %v", err)
+		}
+		if got != "" {
+			t.Errorf("fulcioOIDCIssuer() = %q, want \"\"", got)
+		}
+	})
+}
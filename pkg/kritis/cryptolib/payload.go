@@ -0,0 +1,58 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file documents the SimpleSigning/cosign payload shape that
+// VerifyAttestation would need to recognize alongside the existing
+// AtomicContainerPayload format.
+//
+// NOTE: VerifyAttestation dispatches a verified payload to
+// checkAuthenticatedAttestation(payload, imageName, imageDigest,
+// convertAuthenticatedAttestation), which extracts the image name/digest the
+// payload asserts so they can be checked against the image actually under
+// review. convertAuthenticatedAttestation, the authenticatedAttestation type
+// it returns, and authenticatedAttCheckerImpl are not present in this
+// checkout (nor is pkg/kritis/attestation, whose AtomicContainerPayload the
+// AtomicContainerPayload case would need) — VerifyAttestation already
+// references them as dangling baseline symbols. Teaching that dispatch to
+// also recognize a SimpleSigning/cosign payload (by its critical.type field,
+// matching SimpleSigningType/CosignSigningType below) belongs in
+// convertAuthenticatedAttestation once that baseline gap is filled in; a
+// standalone isSimpleSigningPayload/checkSimpleSigningPayload pair here,
+// never called by anything, would only be dead code asserting it's wired in
+// when it isn't.
+package cryptolib
+
+// The critical.type values that identify a SimpleSigning-shaped payload.
+// SimpleSigningType and CosignSigningType are produced by
+// skopeo/containers-image and sigstore/cosign respectively.
+const (
+	SimpleSigningType = "atomic container signature"
+	CosignSigningType = "cosign container image signature"
+)
+
+// simpleSigningPayload mirrors the shape signer.simpleSigningPayload signs:
+// {"critical": {"identity": {...}, "image": {...}, "type": ...}, "optional": {...}}.
+type simpleSigningPayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
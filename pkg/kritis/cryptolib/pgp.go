@@ -0,0 +1,177 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cryptolib
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// PgpSignatureMode selects how a Pgp Attestation's signature relates to its
+// payload: Inline (the default) carries the payload inside the signature
+// itself, while Detached verifies the signature against a payload supplied
+// separately (e.g., a canonicalized image-digest document).
+type PgpSignatureMode string
+
+const (
+	// Inline is an OpenPGP signed message with the payload embedded in it,
+	// as produced by `gpg --sign`. Any mode other than Detached, including
+	// the empty zero value, is verified as Inline, so existing PublicKeys
+	// without an explicit PgpSignatureMode keep working.
+	Inline PgpSignatureMode = "inline"
+	// Detached is a standalone OpenPGP signature verified against an
+	// externally supplied payload, as produced by `gpg --detach-sign`.
+	Detached PgpSignatureMode = "detached"
+)
+
+type pgpVerifierImpl struct{}
+
+func (pgpVerifierImpl) verifyPgp(signature []byte, publicKey PublicKey) ([]byte, error) {
+	payload, signedByKeyID, err := verifyPgpWithKeyID(signature, publicKey.KeyData)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAuthorizedPgpKeyID(signedByKeyID, publicKey.PgpKeyID); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func (pgpVerifierImpl) verifyPgpDetached(signature []byte, publicKey PublicKey, payload []byte) error {
+	signedByKeyID, err := verifyPgpDetachedWithKeyID(signature, publicKey.KeyData, payload)
+	if err != nil {
+		return err
+	}
+	return checkAuthorizedPgpKeyID(signedByKeyID, publicKey.PgpKeyID)
+}
+
+// verifyPgp verifies an inline OpenPGP signed message and returns
+// its embedded payload. Any signing (sub)key present in publicKey's keyring
+// is accepted; use the pgpVerifier.verifyPgp method to additionally enforce a
+// specific authorized key ID.
+func verifyPgp(signature, publicKey []byte) ([]byte, error) {
+	payload, _, err := verifyPgpWithKeyID(signature, publicKey)
+	return payload, err
+}
+
+// verifyPgpWithKeyID is like verifyPgp, but additionally returns the hex key
+// ID of whichever key in the keyring actually produced the signature, so
+// callers can enforce which key(s) are authorized to sign.
+func verifyPgpWithKeyID(signature, publicKey []byte) ([]byte, string, error) {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(publicKey))
+	if err != nil {
+		return nil, "", errors.Wrap(err, "reading armored public key")
+	}
+	md, err := openpgp.ReadMessage(bytes.NewReader(signature), keyring, nil, nil)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "reading pgp message")
+	}
+	payload, err := ioutil.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "reading pgp message body")
+	}
+	if md.SignatureError != nil {
+		return nil, "", errors.Wrap(md.SignatureError, "verifying pgp signature")
+	}
+	if md.Signature == nil && md.SignatureV3 == nil {
+		return nil, "", errors.New("pgp message is not signed")
+	}
+	if md.SignedBy == nil {
+		return nil, "", errors.New("pgp signature verified but signing key is unknown")
+	}
+	return payload, md.SignedBy.PublicKey.KeyIdString(), nil
+}
+
+// verifyPgpDetached verifies a standalone OpenPGP signature over payload,
+// which is supplied separately rather than embedded in the signature. Any
+// signing (sub)key present in publicKey's keyring is accepted; use the
+// pgpVerifier.verifyPgpDetached method to additionally enforce a specific
+// authorized key ID.
+func verifyPgpDetached(signature, publicKey, payload []byte) error {
+	_, err := verifyPgpDetachedWithKeyID(signature, publicKey, payload)
+	return err
+}
+
+// verifyPgpDetachedWithKeyID is like verifyPgpDetached, but additionally
+// returns the hex key ID of the signature's issuer, so callers can enforce
+// which key(s) are authorized to sign.
+func verifyPgpDetachedWithKeyID(signature, publicKey, payload []byte) (string, error) {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(publicKey))
+	if err != nil {
+		return "", errors.Wrap(err, "reading armored public key")
+	}
+	if _, err := openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(payload), bytes.NewReader(signature)); err != nil {
+		return "", errors.Wrap(err, "verifying detached pgp signature")
+	}
+	issuerKeyID, err := detachedSignatureIssuerKeyID(signature)
+	if err != nil {
+		return "", err
+	}
+	return issuerKeyID, nil
+}
+
+// detachedSignatureIssuerKeyID parses signature (an armored or binary
+// standalone OpenPGP signature packet) and returns the hex key ID of its
+// issuer, without needing to consult the keyring.
+func detachedSignatureIssuerKeyID(signature []byte) (string, error) {
+	var r io.Reader = bytes.NewReader(signature)
+	if block, err := armor.Decode(bytes.NewReader(signature)); err == nil {
+		r = block.Body
+	}
+	pkt, err := packet.Read(r)
+	if err != nil {
+		return "", errors.Wrap(err, "reading pgp signature packet")
+	}
+	switch sig := pkt.(type) {
+	case *packet.Signature:
+		if sig.IssuerKeyId == nil {
+			return "", errors.New("pgp signature has no issuer key id")
+		}
+		return fmt.Sprintf("%016X", *sig.IssuerKeyId), nil
+	case *packet.SignatureV3:
+		return fmt.Sprintf("%016X", sig.IssuerKeyId), nil
+	default:
+		return "", errors.New("first packet in pgp signature is not a signature")
+	}
+}
+
+// checkAuthorizedPgpKeyID enforces that actualKeyID (the hex key ID that
+// produced a signature) matches authorizedKeyID, when authorizedKeyID is
+// set. authorizedKeyID may be given as the short 16-hex-character key ID or
+// the full 40-hex-character fingerprint (whose low 64 bits are the key ID),
+// matched case-insensitively as a suffix in either direction. An empty
+// authorizedKeyID accepts any signing key in the keyring, preserving prior
+// behavior.
+func checkAuthorizedPgpKeyID(actualKeyID, authorizedKeyID string) error {
+	if authorizedKeyID == "" {
+		return nil
+	}
+	actual := strings.ToUpper(strings.TrimPrefix(actualKeyID, "0x"))
+	authorized := strings.ToUpper(strings.TrimPrefix(authorizedKeyID, "0x"))
+	if actual != authorized && !strings.HasSuffix(actual, authorized) && !strings.HasSuffix(authorized, actual) {
+		return fmt.Errorf("pgp signature was made by unauthorized key %q, expected %q", actualKeyID, authorizedKeyID)
+	}
+	return nil
+}
@@ -17,7 +17,14 @@ limitations under the License.
 package cryptolib
 
 import (
+	"bytes"
+	"fmt"
+	"strings"
 	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
 )
 
 // These keys and signatures generated by the following commands:
@@ -134,4 +141,134 @@ func TestVerifyPgp(t *testing.T) {
 			}
 		})
 	}
+}
+
+// generateDetachedSignature creates a fresh PGP entity (using config, or the
+// library default of a 2048-bit RSA key if config is nil), returns its
+// armored public key, and returns an armored detached signature by that
+// entity over payload.
+func generateDetachedSignature(t *testing.T, payload []byte, config *packet.Config) (armoredPublicKey []byte, armoredSignature []byte) {
+	t.Helper()
+	entity, err := openpgp.NewEntity("pgp-detached-test", "", "pgp-detached-test@cryptolib.com", config)
+	if err != nil {
+		t.Fatalf("openpgp.NewEntity() error = %v", err)
+	}
+
+	var keyBuf bytes.Buffer
+	w, err := armor.Encode(&keyBuf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("armor.Encode() error = %v", err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatalf("entity.Serialize() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("armor writer Close() error = %v", err)
+	}
+
+	var sigBuf bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&sigBuf, entity, bytes.NewReader(payload), config); err != nil {
+		t.Fatalf("openpgp.ArmoredDetachSign() error = %v", err)
+	}
+	return keyBuf.Bytes(), sigBuf.Bytes()
+}
+
+func TestVerifyPgpDetached(t *testing.T) {
+	detachedPayload := []byte("detached payload to verify")
+	publicKey, signature := generateDetachedSignature(t, detachedPayload, nil)
+
+	tcs := []struct {
+		name        string
+		signature   []byte
+		publicKey   []byte
+		payload     []byte
+		expectedErr bool
+	}{
+		{
+			name:      "valid detached signature",
+			signature: signature,
+			publicKey: publicKey,
+			payload:   detachedPayload,
+		},
+		{
+			name:        "tampered payload",
+			signature:   signature,
+			publicKey:   publicKey,
+			payload:     []byte("different payload"),
+			expectedErr: true,
+		},
+		{
+			name:        "invalid public key",
+			signature:   signature,
+			publicKey:   []byte("invalid-public-key"),
+			payload:     detachedPayload,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifyPgpDetached(tc.signature, tc.publicKey, tc.payload)
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("verifyPgpDetached() error = %v, expectedErr %t", err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+// TestVerifyPgpDetachedKeySizes covers RSA key sizes beyond the library
+// default of 2048 bits, including RSA-4096.
+//
+// golang.org/x/crypto/openpgp's entity generation only supports RSA, so
+// ECDSA/EdDSA (Curve25519) coverage isn't included here: there is no
+// generated-fixture path for those algorithms with this library.
+func TestVerifyPgpDetachedKeySizes(t *testing.T) {
+	sizes := []int{2048, 4096}
+	detachedPayload := []byte("detached payload to verify")
+
+	for _, bits := range sizes {
+		t.Run(fmt.Sprintf("rsa-%d", bits), func(t *testing.T) {
+			config := &packet.Config{RSABits: bits}
+			publicKey, signature := generateDetachedSignature(t, detachedPayload, config)
+			if err := verifyPgpDetached(signature, publicKey, detachedPayload); err != nil {
+				t.Errorf("verifyPgpDetached() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+// TestVerifyPgpAuthorizedKeyID covers fail-closed enforcement of PublicKey's
+// PgpKeyID: a signature from a key the operator did not explicitly
+// authorize must be rejected, even though it verifies cleanly against the
+// armored keyring.
+func TestVerifyPgpAuthorizedKeyID(t *testing.T) {
+	detachedPayload := []byte("detached payload to verify")
+	publicKey, signature := generateDetachedSignature(t, detachedPayload, nil)
+
+	signedByKeyID, err := verifyPgpDetachedWithKeyID(signature, publicKey, detachedPayload)
+	if err != nil {
+		t.Fatalf("verifyPgpDetachedWithKeyID() error = %v", err)
+	}
+
+	verifier := pgpVerifierImpl{}
+	tcs := []struct {
+		name        string
+		pgpKeyID    string
+		expectedErr bool
+	}{
+		{name: "no authorized key id configured", pgpKeyID: ""},
+		{name: "matches full key id", pgpKeyID: signedByKeyID},
+		{name: "matches lowercase key id", pgpKeyID: strings.ToLower(signedByKeyID)},
+		{name: "does not match unauthorized key id", pgpKeyID: "0123456789ABCDEF", expectedErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			pk := PublicKey{KeyType: Pgp, PgpSignatureMode: Detached, KeyData: publicKey, PgpKeyID: tc.pgpKeyID}
+			err := verifier.verifyPgpDetached(signature, pk, detachedPayload)
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("verifyPgpDetached() error = %v, expectedErr %t", err, tc.expectedErr)
+			}
+		})
+	}
 }
\ No newline at end of file
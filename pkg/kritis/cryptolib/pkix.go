@@ -0,0 +1,118 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cryptolib
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// The SignatureAlgorithm values verifyPkix knows how to verify. SHA256/384/512
+// RSA PKCS1v15 and PSS, plus ECDSA over the NIST P-256/P-384/P-521 curves.
+const (
+	RsaSignPkcs1Sha256 SignatureAlgorithm = "RSASSA_PKCS1V15_SHA256"
+	RsaSignPkcs1Sha384 SignatureAlgorithm = "RSASSA_PKCS1V15_SHA384"
+	RsaSignPkcs1Sha512 SignatureAlgorithm = "RSASSA_PKCS1V15_SHA512"
+	RsaPssSha256       SignatureAlgorithm = "RSASSA_PSS_SHA256"
+	RsaPssSha384       SignatureAlgorithm = "RSASSA_PSS_SHA384"
+	RsaPssSha512       SignatureAlgorithm = "RSASSA_PSS_SHA512"
+	EcdsaP256Sha256    SignatureAlgorithm = "ECDSA_P256_SHA256"
+	EcdsaP384Sha384    SignatureAlgorithm = "ECDSA_P384_SHA384"
+	EcdsaP521Sha512    SignatureAlgorithm = "ECDSA_P521_SHA512"
+)
+
+type pkixVerifierImpl struct{}
+
+// verifyPkix verifies a PKIX signature over payload (for a DSSE envelope,
+// payload is the envelope's PAE encoding).
+// publicKey.SignatureAlgorithm selects both the digest and the verification
+// scheme (RSA PKCS1v15, RSA PSS, or ECDSA); publicKey.KeyData is the DER or
+// PEM encoding of the corresponding PKIX public key.
+func (v pkixVerifierImpl) verifyPkix(signature []byte, payload []byte, publicKey PublicKey) error {
+	hash, err := hashForSignatureAlgorithm(publicKey.SignatureAlgorithm)
+	if err != nil {
+		return err
+	}
+	pub, err := parsePkixPublicKey(publicKey.KeyData)
+	if err != nil {
+		return err
+	}
+	hasher := hash.New()
+	hasher.Write(payload)
+	digest := hasher.Sum(nil)
+
+	switch publicKey.SignatureAlgorithm {
+	case RsaSignPkcs1Sha256, RsaSignPkcs1Sha384, RsaSignPkcs1Sha512:
+		rsaKey, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("signature algorithm %q requires an RSA public key", publicKey.SignatureAlgorithm)
+		}
+		return rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature)
+	case RsaPssSha256, RsaPssSha384, RsaPssSha512:
+		rsaKey, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("signature algorithm %q requires an RSA public key", publicKey.SignatureAlgorithm)
+		}
+		return rsa.VerifyPSS(rsaKey, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash})
+	case EcdsaP256Sha256, EcdsaP384Sha384, EcdsaP521Sha512:
+		ecKey, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("signature algorithm %q requires an ECDSA public key", publicKey.SignatureAlgorithm)
+		}
+		if !ecdsa.VerifyASN1(ecKey, digest, signature) {
+			return errors.New("ecdsa signature verification failed")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", publicKey.SignatureAlgorithm)
+	}
+}
+
+// hashForSignatureAlgorithm returns the digest algorithm a SignatureAlgorithm
+// signs over.
+func hashForSignatureAlgorithm(alg SignatureAlgorithm) (crypto.Hash, error) {
+	switch alg {
+	case RsaSignPkcs1Sha256, RsaPssSha256, EcdsaP256Sha256:
+		return crypto.SHA256, nil
+	case RsaSignPkcs1Sha384, RsaPssSha384, EcdsaP384Sha384:
+		return crypto.SHA384, nil
+	case RsaSignPkcs1Sha512, RsaPssSha512, EcdsaP521Sha512:
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported signature algorithm %q", alg)
+	}
+}
+
+// parsePkixPublicKey parses keyData as a PKIX-encoded public key, accepting
+// either raw DER or a PEM block wrapping it.
+func parsePkixPublicKey(keyData []byte) (crypto.PublicKey, error) {
+	der := keyData
+	if block, _ := pem.Decode(keyData); block != nil {
+		der = block.Bytes
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing PKIX public key")
+	}
+	return pub, nil
+}
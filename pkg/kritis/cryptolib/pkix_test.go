@@ -0,0 +1,139 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cryptolib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"testing"
+)
+
+func signForTest(t *testing.T, alg SignatureAlgorithm, payload []byte) (signature []byte, publicKeyDER []byte) {
+	t.Helper()
+	hash, err := hashForSignatureAlgorithm(alg)
+	if err != nil {
+		t.Fatalf("hashForSignatureAlgorithm() error = %v", err)
+	}
+	hasher := hash.New()
+	hasher.Write(payload)
+	digest := hasher.Sum(nil)
+
+	switch alg {
+	case RsaSignPkcs1Sha256, RsaSignPkcs1Sha384, RsaSignPkcs1Sha512:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("rsa.GenerateKey() error = %v", err)
+		}
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, hash, digest)
+		if err != nil {
+			t.Fatalf("rsa.SignPKCS1v15() error = %v", err)
+		}
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			t.Fatalf("x509.MarshalPKIXPublicKey() error = %v", err)
+		}
+		return sig, der
+	case RsaPssSha256, RsaPssSha384, RsaPssSha512:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("rsa.GenerateKey() error = %v", err)
+		}
+		sig, err := rsa.SignPSS(rand.Reader, key, hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash})
+		if err != nil {
+			t.Fatalf("rsa.SignPSS() error = %v", err)
+		}
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			t.Fatalf("x509.MarshalPKIXPublicKey() error = %v", err)
+		}
+		return sig, der
+	case EcdsaP256Sha256, EcdsaP384Sha384, EcdsaP521Sha512:
+		var curve elliptic.Curve
+		switch alg {
+		case EcdsaP256Sha256:
+			curve = elliptic.P256()
+		case EcdsaP384Sha384:
+			curve = elliptic.P384()
+		default:
+			curve = elliptic.P521()
+		}
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+		}
+		sig, err := ecdsa.SignASN1(rand.Reader, key, digest)
+		if err != nil {
+			t.Fatalf("ecdsa.SignASN1() error = %v", err)
+		}
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			t.Fatalf("x509.MarshalPKIXPublicKey() error = %v", err)
+		}
+		return sig, der
+	default:
+		t.Fatalf("unsupported signature algorithm %q", alg)
+		return nil, nil
+	}
+}
+
+func TestVerifyPkix(t *testing.T) {
+	payload := []byte("payload to verify")
+	algs := []SignatureAlgorithm{
+		RsaSignPkcs1Sha256, RsaSignPkcs1Sha384, RsaSignPkcs1Sha512,
+		RsaPssSha256, RsaPssSha384, RsaPssSha512,
+		EcdsaP256Sha256, EcdsaP384Sha384, EcdsaP521Sha512,
+	}
+	verifier := pkixVerifierImpl{}
+
+	for _, alg := range algs {
+		t.Run(string(alg), func(t *testing.T) {
+			signature, keyData := signForTest(t, alg, payload)
+			publicKey := PublicKey{KeyType: Pkix, SignatureAlgorithm: alg, KeyData: keyData}
+			if err := verifier.verifyPkix(signature, payload, publicKey); err != nil {
+				t.Errorf("verifyPkix() error = %v, want nil", err)
+			}
+		})
+	}
+
+	t.Run("tampered payload", func(t *testing.T) {
+		signature, keyData := signForTest(t, RsaSignPkcs1Sha256, payload)
+		publicKey := PublicKey{KeyType: Pkix, SignatureAlgorithm: RsaSignPkcs1Sha256, KeyData: keyData}
+		if err := verifier.verifyPkix(signature, []byte("different payload"), publicKey); err == nil {
+			t.Error("verifyPkix() error = nil, want error for tampered payload")
+		}
+	})
+
+	t.Run("key type does not match algorithm", func(t *testing.T) {
+		_, ecdsaKeyData := signForTest(t, EcdsaP256Sha256, payload)
+		signature, _ := signForTest(t, RsaSignPkcs1Sha256, payload)
+		publicKey := PublicKey{KeyType: Pkix, SignatureAlgorithm: RsaSignPkcs1Sha256, KeyData: ecdsaKeyData}
+		if err := verifier.verifyPkix(signature, payload, publicKey); err == nil {
+			t.Error("verifyPkix() error = nil, want error for RSA algorithm with an ECDSA key")
+		}
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		_, keyData := signForTest(t, RsaSignPkcs1Sha256, payload)
+		publicKey := PublicKey{KeyType: Pkix, SignatureAlgorithm: SignatureAlgorithm("bogus"), KeyData: keyData}
+		if err := verifier.verifyPkix([]byte("sig"), payload, publicKey); err == nil {
+			t.Error("verifyPkix() error = nil, want error for unsupported algorithm")
+		}
+	})
+}
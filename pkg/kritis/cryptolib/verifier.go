@@ -43,12 +43,46 @@ type PublicKey struct {
 	KeyType KeyType
 	// Signature Algorithm holds the signing and padding algorithm for the signature.
 	SignatureAlgorithm SignatureAlgorithm
+	// PgpSignatureMode selects how a Pgp Attestation's signature relates to
+	// its payload. Only used when KeyType is Pgp; defaults to Inline.
+	PgpSignatureMode PgpSignatureMode
+	// PgpKeyID, if set, restricts verification to signatures made by this
+	// specific OpenPGP key ID or fingerprint. Only used when KeyType is Pgp.
+	// If empty, any signing (sub)key present in KeyData's armored keyring is
+	// accepted, which is unsafe for keyrings containing multiple signing
+	// subkeys the operator does not equally trust.
+	PgpKeyID string
 	// KeyData holds the raw key material which can verify a signature.
 	KeyData []byte
 	// ID uniquely identifies this public key. For PGP, this should be the
 	// OpenPGP RFC4880 V4 fingerprint of the key. For PKIX and JWT, this should
 	// be a StringOrURI: it must either not contain ":" or be a valid URI.
 	ID string
+
+	// The following fields are only used when KeyType is Keyless, where trust
+	// comes from a Fulcio certificate and Rekor inclusion proof rather than a
+	// pre-shared key.
+
+	// FulcioRoot is the PEM-encoded Fulcio CA root certificate that ephemeral
+	// signing certificates must chain to.
+	FulcioRoot []byte
+	// SubjectRegexp, if set, must match an email or URI SAN on the
+	// certificate.
+	SubjectRegexp string
+	// IssuerRegexp, if set, must match the certificate's OIDC issuer.
+	IssuerRegexp string
+	// CertificateIdentity, if set, must exactly equal an email or URI SAN on
+	// the certificate. This is the exact-match counterpart to SubjectRegexp,
+	// matching cosign's `--certificate-identity` (as opposed to
+	// `--certificate-identity-regexp`).
+	CertificateIdentity string
+	// CertificateOIDCIssuer, if set, must exactly equal the certificate's
+	// OIDC issuer. This is the exact-match counterpart to IssuerRegexp,
+	// matching cosign's `--certificate-oidc-issuer`.
+	CertificateOIDCIssuer string
+	// RekorPublicKey is the PEM-encoded public key of the trusted Rekor
+	// transparency log.
+	RekorPublicKey []byte
 }
 
 // NewPublicKey creates a new PublicKey. `keyType` contains the type of the
@@ -109,11 +143,14 @@ func extractPkixKeyID(keyData []byte, keyID string) (string, error) {
 }
 
 type pkixVerifier interface {
-	verifyPkix(signature []byte, payload []byte, publicKey []byte) error
+	verifyPkix(signature []byte, payload []byte, publicKey PublicKey) error
 }
 
 type pgpVerifier interface {
-	verifyPgp(signature, publicKey []byte) ([]byte, error)
+	verifyPgp(signature []byte, publicKey PublicKey) ([]byte, error)
+	// verifyPgpDetached verifies signature, a standalone OpenPGP signature,
+	// against payload supplied separately from the signature itself.
+	verifyPgpDetached(signature []byte, publicKey PublicKey, payload []byte) error
 }
 
 type jwtVerifier interface {
@@ -136,6 +173,8 @@ type verifier struct {
 	pkixVerifier
 	pgpVerifier
 	jwtVerifier
+	dsseVerifier
+	keylessVerifier
 	authenticatedAttChecker
 }
 
@@ -161,6 +200,8 @@ func NewVerifier(image string, publicKeySet []PublicKey) (Verifier, error) {
 		pkixVerifier:            pkixVerifierImpl{},
 		pgpVerifier:             pgpVerifierImpl{},
 		jwtVerifier:             jwtVerifierImpl{},
+		dsseVerifier:            dsseVerifierImpl{pkix: pkixVerifierImpl{}},
+		keylessVerifier:         keylessVerifierImpl{},
 		authenticatedAttChecker: authenticatedAttCheckerImpl{},
 	}, nil
 }
@@ -178,6 +219,16 @@ func indexPublicKeysByID(publicKeyset []PublicKey) map[string]PublicKey {
 
 // VerifyAttestation verifies an Attestation. See Verifier for more details.
 func (v *verifier) VerifyAttestation(att *Attestation) error {
+	// DSSE envelopes carry their own per-signature key IDs in
+	// SerializedPayload, so they bypass the single-PublicKeyID lookup below.
+	if att.PublicKeyID == DsseKeyID {
+		payload, err := v.verifyDsse(att.SerializedPayload, v.PublicKeys)
+		if err != nil {
+			return err
+		}
+		return v.checkAuthenticatedAttestation(payload, v.ImageName, v.ImageDigest, convertAuthenticatedAttestation)
+	}
+
 	// Extract the public key from `publicKeySet` whose ID matches the one in
 	// `att`.
 	publicKey, ok := v.PublicKeys[att.PublicKeyID]
@@ -189,12 +240,19 @@ func (v *verifier) VerifyAttestation(att *Attestation) error {
 	payload := []byte{}
 	switch publicKey.KeyType {
 	case Pkix:
-		err = v.verifyPkix(att.Signature, att.SerializedPayload, publicKey.KeyData)
+		err = v.verifyPkix(att.Signature, att.SerializedPayload, publicKey)
 		payload = att.SerializedPayload
 	case Pgp:
-		payload, err = v.verifyPgp(att.Signature, publicKey.KeyData)
+		if publicKey.PgpSignatureMode == Detached {
+			err = v.verifyPgpDetached(att.Signature, publicKey, att.SerializedPayload)
+			payload = att.SerializedPayload
+		} else {
+			payload, err = v.verifyPgp(att.Signature, publicKey)
+		}
 	case Jwt:
 		payload, err = v.verifyJwt(att.Signature, publicKey)
+	case Keyless:
+		payload, err = v.verifyKeyless(att.Signature, att.SerializedPayload, publicKey)
 	default:
 		return errors.New("signature uses an unsupported key mode")
 	}
@@ -209,9 +267,3 @@ func (v *verifier) VerifyAttestation(att *Attestation) error {
 	// can trust.
 	return v.checkAuthenticatedAttestation(payload, v.ImageName, v.ImageDigest, convertAuthenticatedAttestation)
 }
-
-type pkixVerifierImpl struct{}
-
-func (v pkixVerifierImpl) verifyPkix(signature []byte, payload []byte, publicKey []byte) error {
-	return errors.New("verify pkix not implemented")
-}
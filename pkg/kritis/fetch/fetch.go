@@ -0,0 +1,98 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fetch retrieves signatures and public keys from remote stores
+// (Grafeas, OCI registries, plain HTTP endpoints) over TLS, so an
+// AttestationAuthority can point at an external key/signature service
+// instead of relying solely on in-cluster CRDs.
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// Config configures the TLS transport a Fetcher uses to connect to a remote
+// key/signature store.
+type Config struct {
+	// CACertFile is a PEM file of CA certificates the remote store's
+	// certificate must chain to. If empty, the system root CAs are used.
+	CACertFile string
+	// TLSCertFile and TLSKeyFile are a PEM client certificate/key pair
+	// presented for mutual TLS. Both must be set together, or both left
+	// empty to disable client certificates.
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSServerName overrides the server name used for certificate SAN
+	// verification and SNI, e.g., when the remote store is reached via an
+	// address that doesn't match the certificate.
+	TLSServerName string
+}
+
+// Fetcher retrieves raw bytes (signatures, public keys) from a remote store
+// over TLS. Its client certificate and CA pool are reloaded from disk
+// whenever the configured files change, so certificates can be rotated
+// without restarting the process.
+type Fetcher struct {
+	httpClient *http.Client
+	reloader   *certReloader
+}
+
+// New creates a Fetcher configured by cfg. The returned Fetcher's Close
+// method must be called to stop watching the configured cert files.
+func New(cfg Config) (*Fetcher, error) {
+	reloader, err := newCertReloader(cfg)
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{
+		DialTLSContext: reloader.dialTLSContext,
+	}
+	return &Fetcher{
+		httpClient: &http.Client{Transport: transport},
+		reloader:   reloader,
+	}, nil
+}
+
+// Get fetches url and returns its response body.
+func (f *Fetcher) Get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetching %s", url)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading response body from %s", url)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: unexpected status %d: %s", url, resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// Close stops watching the configured cert files for changes.
+func (f *Fetcher) Close() error {
+	return f.reloader.Close()
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fetch
+
+import (
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// writeCACertFile PEM-encodes server's leaf certificate and writes it to a
+// file under t.TempDir(), returning the file's path.
+func writeCACertFile(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := ioutil.WriteFile(path, pemBytes, 0600); err != nil {
+		t.Fatalf("writing ca cert file: %v", err)
+	}
+	return path
+}
+
+func TestFetcherGet(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello from the remote store"))
+	}))
+	defer server.Close()
+	caCertFile := writeCACertFile(t, server)
+
+	f, err := New(Config{CACertFile: caCertFile})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer f.Close()
+
+	body, err := f.Get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(body) != "hello from the remote store" {
+		t.Errorf("Get() = %q, want %q", body, "hello from the remote store")
+	}
+}
+
+func TestFetcherGetRejectsUnknownCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("should not be reached"))
+	}))
+	defer server.Close()
+
+	// No CACertFile configured: the fetcher falls back to system root CAs,
+	// which do not trust the server's self-signed certificate.
+	f, err := New(Config{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Get(context.Background(), server.URL); err == nil {
+		t.Error("Get() error = nil, want error for an untrusted CA")
+	}
+}
+
+func TestFetcherGetRejectsWrongServerName(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("should not be reached"))
+	}))
+	defer server.Close()
+	caCertFile := writeCACertFile(t, server)
+
+	// httptest's certificate covers example.com and *.example.com, so the
+	// name must lie outside both to cause a SAN mismatch.
+	f, err := New(Config{CACertFile: caCertFile, TLSServerName: "wrong-server-name.invalid"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Get(context.Background(), server.URL); err == nil {
+		t.Error("Get() error = nil, want error for a SAN mismatch")
+	}
+}
+
+func TestFetcherRejectsMismatchedClientCertConfig(t *testing.T) {
+	if _, err := New(Config{TLSCertFile: "cert.pem"}); err == nil {
+		t.Error("New() error = nil, want error when only tls-cert-file is set")
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fetch
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// certReloader holds the TLS client certificate and CA pool built from
+// Config, reloading them from disk whenever the underlying files change.
+type certReloader struct {
+	cfg Config
+
+	mu     sync.RWMutex
+	cert   *tls.Certificate
+	caPool *x509.CertPool
+
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+func newCertReloader(cfg Config) (*certReloader, error) {
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return nil, errors.New("tls-cert-file and tls-key-file must be set together")
+	}
+
+	r := &certReloader{cfg: cfg, done: make(chan struct{})}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating fsnotify watcher")
+	}
+	for _, file := range []string{cfg.CACertFile, cfg.TLSCertFile, cfg.TLSKeyFile} {
+		if file == "" {
+			continue
+		}
+		if err := watcher.Add(file); err != nil {
+			watcher.Close()
+			return nil, errors.Wrapf(err, "watching %s for changes", file)
+		}
+	}
+	r.watcher = watcher
+	go r.watchLoop()
+	return r, nil
+}
+
+// reload reads the configured CA and client certificate files from disk and
+// swaps them in atomically.
+func (r *certReloader) reload() error {
+	var cert *tls.Certificate
+	if r.cfg.TLSCertFile != "" {
+		c, err := tls.LoadX509KeyPair(r.cfg.TLSCertFile, r.cfg.TLSKeyFile)
+		if err != nil {
+			return errors.Wrap(err, "loading tls client certificate")
+		}
+		cert = &c
+	}
+
+	var caPool *x509.CertPool
+	if r.cfg.CACertFile != "" {
+		pemBytes, err := ioutil.ReadFile(r.cfg.CACertFile)
+		if err != nil {
+			return errors.Wrap(err, "reading ca cert file")
+		}
+		caPool = x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(pemBytes) {
+			return errors.New("ca cert file contains no valid certificates")
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = cert
+	r.caPool = caPool
+	return nil
+}
+
+func (r *certReloader) watchLoop() {
+	for {
+		select {
+		case event, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			if err := r.reload(); err != nil {
+				glog.Warningf("fetch: failed to reload TLS credentials after %s changed: %v", event.Name, err)
+			} else {
+				glog.Infof("fetch: reloaded TLS credentials after %s changed.", event.Name)
+			}
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			glog.Warningf("fetch: watching TLS credential files failed: %v", err)
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// tlsConfig returns a TLS config reflecting the most recently loaded
+// certificate and CA pool.
+func (r *certReloader) tlsConfig() *tls.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cfg := &tls.Config{
+		RootCAs:    r.caPool,
+		ServerName: r.cfg.TLSServerName,
+	}
+	if r.cert != nil {
+		cfg.Certificates = []tls.Certificate{*r.cert}
+	}
+	return cfg
+}
+
+// dialTLSContext dials addr using the most recently loaded TLS config,
+// ensuring in-flight dials always see up-to-date certificates even if a
+// reload happens mid-connection-pool-lifetime.
+func (r *certReloader) dialTLSContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &tls.Dialer{Config: r.tlsConfig()}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// Close stops watching the configured cert files for changes.
+func (r *certReloader) Close() error {
+	close(r.done)
+	return r.watcher.Close()
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rego evaluates a user-supplied Rego/OPA module as an alternative
+// to kritis's built-in severity threshold checks, so operators can
+// express policies (e.g. "deny if any CVE published in the last 7 days has
+// a fix") without extending a CRD's schema for every new rule.
+//
+// NOTE: this package implements module loading, compiling, caching, and
+// evaluation in full, using the real github.com/open-policy-agent/opa/rego
+// API. It stops short of two things a full integration needs:
+//
+//   - PolicyRef below is this package's own stand-in for what should
+//     eventually be a vsp.Spec.RegoPolicyRef field on the v1beta1
+//     VulnzSigningPolicy spec, so callers wouldn't need this package to
+//     define its own ref type. v1beta1 is not present in this checkout, so
+//     that field can't be added there yet; vulnzsigningpolicy would convert
+//     it to a PolicyRef when calling Evaluate once it exists.
+//   - Converting an evaluation Result into a policy.Violation:
+//     vulnzsigningpolicy.Violation's fields are unexported (vulnerability,
+//     vType, reason) and policy.Violation itself is not present in this
+//     checkout, so neither its construction contract nor the vType/Reason
+//     values it expects can be seen from here. Converting Result into
+//     policy.Violation is left to vulnzsigningpolicy.ValidateVulnzSigningPolicy,
+//     the one place that can already construct its own Violation values,
+//     once it checks vsp.Spec.RegoPolicyRef and bypasses
+//     severityWithinThreshold in favor of an Evaluator.
+package rego
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/grafeas/kritis/pkg/attestlib"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// PolicyRef identifies a Rego module to load, e.g. a key within a ConfigMap.
+// It mirrors the shape the eventual v1beta1.VulnzSigningPolicy.Spec.RegoPolicyRef
+// field would have; see the package doc for why it lives here instead.
+type PolicyRef struct {
+	ConfigMapName string
+	Key           string
+}
+
+// PolicyMeta identifies the policy being evaluated, made available to the
+// Rego module as input.policyMeta.
+type PolicyMeta struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// Input is the value evaluated against a Rego module.
+type Input struct {
+	Image           string                   `json:"image"`
+	Vulnerabilities []metadata.Vulnerability `json:"vulnerabilities"`
+	Attestations    []attestlib.Attestation  `json:"attestations"`
+	PolicyMeta      PolicyMeta               `json:"policyMeta"`
+}
+
+// Result is a single violation object returned by a Rego module's
+// data.kritis.deny rule.
+type Result struct {
+	Msg      string `json:"msg"`
+	VType    string `json:"vType"`
+	Severity string `json:"severity"`
+}
+
+// ModuleLoader resolves a PolicyRef to the Rego module source it names,
+// e.g. by reading a key out of a ConfigMap.
+type ModuleLoader func(ctx context.Context, ref PolicyRef) (string, error)
+
+// Evaluator compiles and evaluates Rego modules resolved by a ModuleLoader.
+// Compiled modules are cached by the sha256 hash of their source, so a
+// policy that hasn't changed isn't recompiled on every review.
+type Evaluator struct {
+	loadModule ModuleLoader
+
+	mu      sync.Mutex
+	queries map[string]rego.PreparedEvalQuery
+}
+
+// NewEvaluator returns an Evaluator that resolves PolicyRefs via loadModule.
+func NewEvaluator(loadModule ModuleLoader) *Evaluator {
+	return &Evaluator{
+		loadModule: loadModule,
+		queries:    make(map[string]rego.PreparedEvalQuery),
+	}
+}
+
+// Evaluate compiles (or reuses a cached compilation of) the module ref
+// refers to and evaluates it against in, returning the Results its
+// data.kritis.deny rule produces.
+func (e *Evaluator) Evaluate(ctx context.Context, ref PolicyRef, in Input) ([]Result, error) {
+	query, err := e.preparedQuery(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	rs, err := query.Eval(ctx, rego.EvalInput(in))
+	if err != nil {
+		return nil, fmt.Errorf("evaluating rego policy %s/%s failed: %v", ref.ConfigMapName, ref.Key, err)
+	}
+
+	var results []Result
+	if err := decodeResultSet(rs, &results); err != nil {
+		return nil, fmt.Errorf("decoding rego policy %s/%s result failed: %v", ref.ConfigMapName, ref.Key, err)
+	}
+	return results, nil
+}
+
+// preparedQuery returns the compiled data.kritis.deny query for the module
+// ref refers to, compiling and caching it if this is the first time its
+// current source has been seen.
+func (e *Evaluator) preparedQuery(ctx context.Context, ref PolicyRef) (rego.PreparedEvalQuery, error) {
+	source, err := e.loadModule(ctx, ref)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, fmt.Errorf("loading rego module %s/%s failed: %v", ref.ConfigMapName, ref.Key, err)
+	}
+	key := moduleCacheKey(source)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if query, ok := e.queries[key]; ok {
+		return query, nil
+	}
+
+	query, err := rego.New(
+		rego.Query("data.kritis.deny"),
+		rego.Module(ref.ConfigMapName+"/"+ref.Key, source),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, fmt.Errorf("compiling rego module %s/%s failed: %v", ref.ConfigMapName, ref.Key, err)
+	}
+	e.queries[key] = query
+	return query, nil
+}
+
+// moduleCacheKey hashes source so two refs pointing at identically-worded
+// modules, or the same ref re-read after a no-op ConfigMap update, share a
+// cache entry instead of recompiling.
+func moduleCacheKey(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
+
+// decodeResultSet decodes rs's first expression value (the array the
+// data.kritis.deny rule evaluated to) into out.
+func decodeResultSet(rs rego.ResultSet, out *[]Result) error {
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(rs[0].Expressions[0].Value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
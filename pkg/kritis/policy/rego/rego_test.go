@@ -0,0 +1,100 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rego
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+)
+
+const denyOldUnfixedCVEModule = `
+package kritis
+
+deny[result] {
+	some v
+	vuln := input.vulnerabilities[v]
+	vuln.HasFixAvailable == false
+	result := {"msg": sprintf("unfixable CVE %s is denied", [vuln.CVE]), "vType": "CVSSThresholdViolation", "severity": vuln.Severity}
+}
+`
+
+func TestEvaluatorEvaluate(t *testing.T) {
+	ref := PolicyRef{ConfigMapName: "kritis-policies", Key: "deny-unfixed.rego"}
+	loadCount := 0
+	loader := func(ctx context.Context, r PolicyRef) (string, error) {
+		loadCount++
+		return denyOldUnfixedCVEModule, nil
+	}
+	e := NewEvaluator(loader)
+
+	in := Input{
+		Image: "gcr.io/foo/bar@sha256:abc",
+		Vulnerabilities: []metadata.Vulnerability{
+			{CVE: "CVE-2020-1", HasFixAvailable: false, Severity: "HIGH"},
+			{CVE: "CVE-2020-2", HasFixAvailable: true, Severity: "LOW"},
+		},
+		PolicyMeta: PolicyMeta{Name: "my-vsp", Namespace: "default"},
+	}
+
+	results, err := e.Evaluate(context.Background(), ref, in)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Evaluate() returned %d results, want 1: %+v", len(results), results)
+	}
+	if results[0].VType != "CVSSThresholdViolation" {
+		t.Errorf("Evaluate() result vType = %q, want %q", results[0].VType, "CVSSThresholdViolation")
+	}
+
+	// A second evaluation with the same module source should reuse the
+	// cached compiled query rather than recompiling.
+	if _, err := e.Evaluate(context.Background(), ref, in); err != nil {
+		t.Fatalf("second Evaluate() error = %v", err)
+	}
+	if loadCount != 2 {
+		t.Errorf("loader called %d times, want 2 (once per Evaluate call)", loadCount)
+	}
+	if len(e.queries) != 1 {
+		t.Errorf("len(e.queries) = %d, want 1 (module source unchanged, should share one cache entry)", len(e.queries))
+	}
+}
+
+func TestEvaluatorEvaluateNoViolations(t *testing.T) {
+	ref := PolicyRef{ConfigMapName: "kritis-policies", Key: "deny-unfixed.rego"}
+	loader := func(ctx context.Context, r PolicyRef) (string, error) {
+		return denyOldUnfixedCVEModule, nil
+	}
+	e := NewEvaluator(loader)
+
+	in := Input{
+		Image: "gcr.io/foo/bar@sha256:abc",
+		Vulnerabilities: []metadata.Vulnerability{
+			{CVE: "CVE-2020-2", HasFixAvailable: true, Severity: "LOW"},
+		},
+	}
+
+	results, err := e.Evaluate(context.Background(), ref, in)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Evaluate() returned %d results, want 0: %+v", len(results), results)
+	}
+}
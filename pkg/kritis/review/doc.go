@@ -0,0 +1,42 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package review implements ReviewGAP/ReviewISP, which decide whether an
+// image satisfies a GenericAttestationPolicy or ImageSecurityPolicy by
+// fetching and verifying attestations against the policy's configured
+// AttestationAuthorities.
+//
+// NOTE: this package's source is not present in this checkout (only
+// review_test.go is), so it can't be extended here. Several outstanding
+// requests depend on it:
+//
+//   - Per-authority image pull credentials: Config needs a SecretFetcher
+//     that resolves an AttestationAuthoritySpec's new
+//     ImagePullSecrets []corev1.LocalObjectReference into a registry
+//     keychain, so ReviewGAP/ReviewISP can fetch attestations/occurrences
+//     from a private registry or note project instead of assuming ambient
+//     service-account credentials. MockMetadataClient (in review_test.go)
+//     would need to assert the resolved credentials per authority.
+//   - Scoped enforcement actions and keyless (Fulcio/Rekor) attestor
+//     trust, described where pkg/kritis/crd/vulnzsigningpolicy and
+//     pkg/kritis/signer/predicate.go note the same gap.
+//   - An offline policy-tester CLI (cmd/kritis-tester) needs a
+//     review.NewFromFiles(...) helper that builds a Config from local
+//     policy/attestation/vulnerability files instead of a live cluster, so
+//     the CLI and ReviewGAP/ReviewISP's tests can share the same
+//     construction code the duplicated test setup currently inlines. That
+//     helper, and the CLI itself, are left unwritten for the same reason.
+package review
@@ -0,0 +1,163 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/grafeas/kritis/pkg/attestlib"
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+// SignResult carries the outcome of signing a single image through
+// SignImages.
+type SignResult struct {
+	Image       string
+	Attestation *attestlib.Attestation
+	Err         error
+	// Skipped is true when the image already had an attestation and the
+	// signer is configured not to overwrite it.
+	Skipped bool
+}
+
+// batchAttestationChecker is implemented by metadata clients that can check
+// for existing attestations across many images in a single round trip.
+// SignImages uses it when available instead of checking each image in turn.
+type batchAttestationChecker interface {
+	BatchAttestations(images []string, aa *v1beta1.AttestationAuthority) (map[string][]attestlib.Attestation, error)
+}
+
+// SignImages signs each of images concurrently, using up to concurrency
+// worker goroutines, and streams one SignResult per image on the returned
+// channel as it becomes available. The channel is closed once every image
+// has a result; see runConcurrent for images left when ctx is done. All images share s's underlying
+// metadata.ReadWriteClient and note, which is only looked up/created once.
+func (s Signer) SignImages(ctx context.Context, images []string, concurrency int) <-chan SignResult {
+	existing := s.batchExistingAttestations(images)
+	return runConcurrent(ctx, images, concurrency, func(image string) SignResult {
+		return s.signImageResult(image, existing)
+	})
+}
+
+// runConcurrent runs fn(image) for each of images using up to concurrency
+// worker goroutines, streaming one SignResult per image on the returned
+// channel as it becomes available. The channel is closed once every image
+// has a result; images still waiting for a worker when ctx is done may be
+// reported with ctx.Err() instead of running fn.
+func runConcurrent(ctx context.Context, images []string, concurrency int, fn func(image string) SignResult) <-chan SignResult {
+	results := make(chan SignResult, len(images))
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, image := range images {
+		image := image
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results <- SignResult{Image: image, Err: ctx.Err()}
+				return
+			}
+			results <- fn(image)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// signImageResult is the per-image body of SignImages: it mirrors SignImage
+// but reports its outcome as a SignResult instead of returning an error, and
+// can consult a pre-computed existence map instead of querying per image.
+func (s Signer) signImageResult(image string, existing map[string]bool) SignResult {
+	skip, err := s.shouldSkip(image, existing)
+	if err != nil {
+		return SignResult{Image: image, Err: fmt.Errorf("checking existing attestation status failed: %v", err)}
+	}
+	if skip {
+		return SignResult{Image: image, Skipped: true}
+	}
+
+	att, err := s.createAttestation(image)
+	if err != nil {
+		return SignResult{Image: image, Err: fmt.Errorf("creating attestation failed: %v", err)}
+	}
+	if err := s.uploadAttestation(image, att); err != nil {
+		return SignResult{Image: image, Err: fmt.Errorf("uploading attestation failed: %v", err)}
+	}
+	return SignResult{Image: image, Attestation: att}
+}
+
+// shouldSkip reports whether image already has an attestation that should
+// not be overwritten, deleting any existing attestation otherwise.
+func (s Signer) shouldSkip(image string, existing map[string]bool) (bool, error) {
+	var existed bool
+	var err error
+	if existing != nil {
+		existed = existing[image]
+	} else {
+		existed, err = s.isAttestationAlreadyExist(image)
+		if err != nil {
+			return false, err
+		}
+	}
+	if !existed {
+		return false, nil
+	}
+	if !s.config.overwrite {
+		glog.Warningf("Attestation for image %q already existed and signer is configured not to overwrite.", image)
+		return true, nil
+	}
+	glog.Infof("Deleting existing attestation for image %q because signer.config.overwrite=True.", image)
+	if err := s.client.DeleteAttestationOccurrence(image, &s.config.authority); err != nil {
+		return false, fmt.Errorf("deleting existing attestation failed: %v", err)
+	}
+	return false, nil
+}
+
+// batchExistingAttestations reports, for each of images, whether it already
+// has an attestation, using a single batched call when s.client supports it.
+// It returns nil if the client doesn't support batching, in which case each
+// image's existence is instead checked individually as it is signed.
+func (s Signer) batchExistingAttestations(images []string) map[string]bool {
+	batcher, ok := s.client.(batchAttestationChecker)
+	if !ok {
+		return nil
+	}
+	attsByImage, err := batcher.BatchAttestations(images, &s.config.authority)
+	if err != nil {
+		glog.Warningf("Batched attestation existence check failed, falling back to per-image checks: %v", err)
+		return nil
+	}
+	existing := make(map[string]bool, len(images))
+	for _, image := range images {
+		existing[image] = len(attsByImage[image]) > 0
+	}
+	return existing
+}
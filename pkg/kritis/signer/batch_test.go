@@ -0,0 +1,131 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunConcurrentRespectsConcurrencyLimit(t *testing.T) {
+	images := make([]string, 10)
+	for i := range images {
+		images[i] = fmt.Sprintf("image-%d", i)
+	}
+
+	const concurrency = 3
+	var inFlight, maxInFlight int32
+	var mu sync.Mutex
+	started := make(chan struct{}, len(images))
+
+	results := runConcurrent(context.Background(), images, concurrency, func(image string) SignResult {
+		n := atomic.AddInt32(&inFlight, 1)
+		mu.Lock()
+		if n > maxInFlight {
+			maxInFlight = n
+		}
+		mu.Unlock()
+		started <- struct{}{}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return SignResult{Image: image}
+	})
+
+	seen := map[string]bool{}
+	for r := range results {
+		if r.Err != nil {
+			t.Errorf("SignResult for %q has unexpected error: %v", r.Image, r.Err)
+		}
+		seen[r.Image] = true
+	}
+	if len(seen) != len(images) {
+		t.Errorf("got %d results, want %d", len(seen), len(images))
+	}
+	for _, image := range images {
+		if !seen[image] {
+			t.Errorf("missing SignResult for %q", image)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if maxInFlight > concurrency {
+		t.Errorf("max concurrent workers = %d, want at most %d", maxInFlight, concurrency)
+	}
+}
+
+func TestRunConcurrentNonPositiveConcurrencyTreatedAsOne(t *testing.T) {
+	images := []string{"a", "b", "c"}
+	var maxInFlight int32
+	var inFlight int32
+	var mu sync.Mutex
+
+	results := runConcurrent(context.Background(), images, 0, func(image string) SignResult {
+		n := atomic.AddInt32(&inFlight, 1)
+		mu.Lock()
+		if n > maxInFlight {
+			maxInFlight = n
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return SignResult{Image: image}
+	})
+
+	count := 0
+	for range results {
+		count++
+	}
+	if count != len(images) {
+		t.Errorf("got %d results, want %d", count, len(images))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if maxInFlight > 1 {
+		t.Errorf("max concurrent workers = %d, want 1 when concurrency <= 0", maxInFlight)
+	}
+}
+
+func TestRunConcurrentCancelledContext(t *testing.T) {
+	images := []string{"a", "b", "c", "d"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// With an already-cancelled context, each worker's select picks at random
+	// between acquiring the semaphore and ctx.Done(), so any number of images
+	// may still run fn; every image should still get a SignResult, just with
+	// ctx.Err() for the ones that never ran fn.
+	results := runConcurrent(ctx, images, 1, func(image string) SignResult {
+		return SignResult{Image: image}
+	})
+
+	count := 0
+	for r := range results {
+		count++
+		if r.Err != nil && r.Err != context.Canceled {
+			t.Errorf("SignResult.Err = %v, want nil or context.Canceled", r.Err)
+		}
+	}
+	if count != len(images) {
+		t.Errorf("got %d results, want %d", count, len(images))
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/grafeas/kritis/pkg/attestlib"
+)
+
+// KeylessConfig configures a Fulcio/Rekor-backed keyless signer.
+type KeylessConfig struct {
+	// FulcioURL is the base URL of the Fulcio CA that issues short-lived
+	// code-signing certificates in exchange for an OIDC identity token.
+	FulcioURL string
+	// RekorURL is the base URL of the Rekor transparency log that signatures
+	// are submitted to.
+	RekorURL string
+	// OIDCTokenFile, if set, contains a pre-fetched OIDC identity token.
+	// If empty, the token is read from the file GOOGLE_APPLICATION_CREDENTIALS
+	// names.
+	OIDCTokenFile string
+}
+
+// rekorLogEntry is the subset of a Rekor log entry response that Kritis
+// embeds alongside a keyless signature.
+type rekorLogEntry struct {
+	LogIndex int64  `json:"logIndex"`
+	LogID    string `json:"logID"`
+	// SET is the base64-encoded Signed Entry Timestamp proving inclusion.
+	SET string `json:"set"`
+}
+
+// keylessBundle is the value stored as an Attestation's SerializedPayload
+// when signing keylessly: the signed payload plus everything a verifier
+// needs to establish trust without a pre-shared key.
+type keylessBundle struct {
+	Payload    []byte        `json:"payload"`
+	CertChain  []byte        `json:"certChain"`
+	RekorEntry rekorLogEntry `json:"rekorEntry"`
+}
+
+// KeylessSigner signs attestations with an ephemeral ECDSA P-256 key whose
+// public key is certified by Fulcio and whose signatures are logged to Rekor.
+type KeylessSigner struct {
+	cfg        KeylessConfig
+	key        *ecdsa.PrivateKey
+	httpClient *http.Client
+
+	// certChain holds the PEM-encoded Fulcio certificate chain for the
+	// ephemeral key, populated by NewKeylessSigner.
+	certChain []byte
+}
+
+// NewKeylessSigner generates an ephemeral ECDSA P-256 keypair, exchanges it
+// with Fulcio for a short-lived code-signing certificate using the caller's
+// OIDC identity, and returns an attestlib.Signer that logs every signature it
+// produces to Rekor before returning it.
+func NewKeylessSigner(cfg KeylessConfig) (attestlib.Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating ephemeral keypair failed: %v", err)
+	}
+	s := &KeylessSigner{cfg: cfg, key: key, httpClient: &http.Client{}}
+
+	token, err := s.oidcToken()
+	if err != nil {
+		return nil, fmt.Errorf("obtaining OIDC identity token failed: %v", err)
+	}
+	if err := s.certify(token); err != nil {
+		return nil, fmt.Errorf("requesting fulcio certificate failed: %v", err)
+	}
+	glog.Infof("Obtained fulcio certificate for ephemeral keyless signing key.")
+	return s, nil
+}
+
+func (s *KeylessSigner) oidcToken() (string, error) {
+	path := s.cfg.OIDCTokenFile
+	if path == "" {
+		path = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	if path == "" {
+		return "", fmt.Errorf("no OIDC token source configured: set -oidc_token_file or GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	token, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(token), nil
+}
+
+// certify sends a certificate signing request for s.key to Fulcio,
+// authenticated by oidcToken, and stores the returned certificate chain.
+func (s *KeylessSigner) certify(oidcToken string) error {
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "kritis-keyless-signer"},
+	}, s.key)
+	if err != nil {
+		return fmt.Errorf("creating CSR failed: %v", err)
+	}
+
+	reqBody, err := json.Marshal(struct {
+		CertificateSigningRequest string `json:"certificateSigningRequest"`
+	}{CertificateSigningRequest: base64.StdEncoding.EncodeToString(csr)})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.cfg.FulcioURL+"/api/v1/signingCert", bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+oidcToken)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	chain, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fulcio returned status %d: %s", resp.StatusCode, chain)
+	}
+	s.certChain = chain
+	return nil
+}
+
+// CreateAttestation signs payload with the ephemeral key, submits the
+// signature to Rekor for transparency logging, and returns an Attestation
+// whose SerializedPayload bundles the payload, certificate chain, and Rekor
+// inclusion proof so a keyless-aware Verifier can check it.
+func (s *KeylessSigner) CreateAttestation(payload []byte) (*attestlib.Attestation, error) {
+	digest := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("signing payload failed: %v", err)
+	}
+
+	entry, err := s.logToRekor(payload, sig)
+	if err != nil {
+		return nil, fmt.Errorf("logging signature to rekor failed: %v", err)
+	}
+
+	bundle, err := json.Marshal(keylessBundle{
+		Payload:    payload,
+		CertChain:  s.certChain,
+		RekorEntry: *entry,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling keyless bundle failed: %v", err)
+	}
+
+	return &attestlib.Attestation{
+		PublicKeyID:       "keyless",
+		Signature:         sig,
+		SerializedPayload: bundle,
+	}, nil
+}
+
+// logToRekor submits (payload, sig) to Rekor and returns the resulting log
+// entry, including its inclusion proof.
+func (s *KeylessSigner) logToRekor(payload, sig []byte) (*rekorLogEntry, error) {
+	reqBody, err := json.Marshal(struct {
+		Signature []byte `json:"signature"`
+		Payload   []byte `json:"payload"`
+	}{Signature: sig, Payload: payload})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.cfg.RekorURL+"/api/v1/log/entries", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rekor returned status %d: %s", resp.StatusCode, body)
+	}
+
+	var entry rekorLogEntry
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return nil, fmt.Errorf("parsing rekor response failed: %v", err)
+	}
+	return &entry, nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// writeOIDCTokenFile writes token to a temp file and returns its path, for
+// use as KeylessConfig.OIDCTokenFile.
+func writeOIDCTokenFile(t *testing.T, token string) string {
+	t.Helper()
+	f, err := ioutil.TempFile("", "oidc-token")
+	if err != nil {
+		t.Fatalf("ioutil.TempFile() error = %v", err)
+	}
+	if _, err := f.WriteString(token); err != nil {
+		t.Fatalf("WriteString() error = %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return f.Name()
+}
+
+func newFulcioStub(t *testing.T, wantAuth string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/signingCert" {
+			t.Errorf("fulcio request path = %q, want /api/v1/signingCert", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != wantAuth {
+			t.Errorf("fulcio Authorization header = %q, want %q", got, wantAuth)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("fake-cert-chain"))
+	}))
+}
+
+func newRekorStub(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/log/entries" {
+			t.Errorf("rekor request path = %q, want /api/v1/log/entries", r.URL.Path)
+		}
+		entry := rekorLogEntry{LogIndex: 42, LogID: "test-log", SET: "c2V0"}
+		body, err := json.Marshal(entry)
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}))
+}
+
+func TestNewKeylessSignerAndCreateAttestation(t *testing.T) {
+	fulcio := newFulcioStub(t, "Bearer test-oidc-token")
+	defer fulcio.Close()
+	rekor := newRekorStub(t)
+	defer rekor.Close()
+
+	cfg := KeylessConfig{
+		FulcioURL:     fulcio.URL,
+		RekorURL:      rekor.URL,
+		OIDCTokenFile: writeOIDCTokenFile(t, "test-oidc-token"),
+	}
+
+	s, err := NewKeylessSigner(cfg)
+	if err != nil {
+		t.Fatalf("NewKeylessSigner() error = %v", err)
+	}
+
+	att, err := s.CreateAttestation([]byte("payload to sign"))
+	if err != nil {
+		t.Fatalf("CreateAttestation() error = %v", err)
+	}
+	if att.PublicKeyID != "keyless" {
+		t.Errorf("PublicKeyID = %q, want %q", att.PublicKeyID, "keyless")
+	}
+	if len(att.Signature) == 0 {
+		t.Error("Signature is empty, want a populated ECDSA signature")
+	}
+
+	var bundle keylessBundle
+	if err := json.Unmarshal(att.SerializedPayload, &bundle); err != nil {
+		t.Fatalf("unmarshaling SerializedPayload as keylessBundle: %v", err)
+	}
+	if string(bundle.Payload) != "payload to sign" {
+		t.Errorf("bundle.Payload = %q, want %q", bundle.Payload, "payload to sign")
+	}
+	if string(bundle.CertChain) != "fake-cert-chain" {
+		t.Errorf("bundle.CertChain = %q, want %q", bundle.CertChain, "fake-cert-chain")
+	}
+	if bundle.RekorEntry.LogIndex != 42 || bundle.RekorEntry.LogID != "test-log" {
+		t.Errorf("bundle.RekorEntry = %+v, want LogIndex 42, LogID %q", bundle.RekorEntry, "test-log")
+	}
+}
+
+func TestNewKeylessSignerMissingOIDCToken(t *testing.T) {
+	cfg := KeylessConfig{FulcioURL: "http://unused.invalid", RekorURL: "http://unused.invalid"}
+	if _, err := NewKeylessSigner(cfg); err == nil {
+		t.Error("NewKeylessSigner() error = nil, want error when no OIDC token source is configured")
+	}
+}
+
+func TestNewKeylessSignerFulcioError(t *testing.T) {
+	fulcio := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("permission denied"))
+	}))
+	defer fulcio.Close()
+
+	cfg := KeylessConfig{
+		FulcioURL:     fulcio.URL,
+		RekorURL:      "http://unused.invalid",
+		OIDCTokenFile: writeOIDCTokenFile(t, "test-oidc-token"),
+	}
+	_, err := NewKeylessSigner(cfg)
+	if err == nil {
+		t.Fatal("NewKeylessSigner() error = nil, want error when fulcio rejects the CSR")
+	}
+	if !strings.Contains(err.Error(), "403") {
+		t.Errorf("NewKeylessSigner() error = %v, want it to mention the fulcio status code", err)
+	}
+}
+
+func TestKeylessSignerCreateAttestationRekorError(t *testing.T) {
+	fulcio := newFulcioStub(t, "Bearer test-oidc-token")
+	defer fulcio.Close()
+	rekor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("rekor is down"))
+	}))
+	defer rekor.Close()
+
+	cfg := KeylessConfig{
+		FulcioURL:     fulcio.URL,
+		RekorURL:      rekor.URL,
+		OIDCTokenFile: writeOIDCTokenFile(t, "test-oidc-token"),
+	}
+	s, err := NewKeylessSigner(cfg)
+	if err != nil {
+		t.Fatalf("NewKeylessSigner() error = %v", err)
+	}
+	if _, err := s.CreateAttestation([]byte("payload")); err == nil {
+		t.Error("CreateAttestation() error = nil, want error when rekor submission fails")
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafeas/kritis/pkg/kritis/attestation"
+)
+
+// PayloadFormat selects the wire format that a Signer signs over.
+type PayloadFormat string
+
+const (
+	// AtomicFormat is the existing AtomicContainerPayload format, and the
+	// default when no format is configured.
+	AtomicFormat PayloadFormat = "atomic"
+	// SimpleSigningFormat is the containers/image "simple signing" format
+	// produced by tools like skopeo.
+	SimpleSigningFormat PayloadFormat = "simplesigning"
+	// CosignFormat is the SimpleSigning-shaped payload sigstore/cosign signs.
+	CosignFormat PayloadFormat = "cosign"
+)
+
+const (
+	simpleSigningType = "atomic container signature"
+	cosignSigningType = "cosign container image signature"
+)
+
+// PayloadFormatter builds the bytes that get signed for an image.
+type PayloadFormatter interface {
+	Format(image string) ([]byte, error)
+}
+
+// NewPayloadFormatter returns the PayloadFormatter for format. An empty
+// format returns the default AtomicFormat formatter.
+func NewPayloadFormatter(format PayloadFormat) (PayloadFormatter, error) {
+	switch format {
+	case "", AtomicFormat:
+		return atomicHostSigningFormatter{}, nil
+	case SimpleSigningFormat:
+		return simpleSigningFormatter{critical: simpleSigningType}, nil
+	case CosignFormat:
+		return simpleSigningFormatter{critical: cosignSigningType}, nil
+	default:
+		return nil, fmt.Errorf("unrecognized payload format %q", format)
+	}
+}
+
+type atomicHostSigningFormatter struct{}
+
+func (atomicHostSigningFormatter) Format(image string) ([]byte, error) {
+	return attestation.AtomicContainerPayload(image)
+}
+
+// simpleSigningCritical is the "critical" object shared by the SimpleSigning
+// and cosign payload formats; only "type" differs between the two.
+type simpleSigningCritical struct {
+	Identity struct {
+		DockerReference string `json:"docker-reference"`
+	} `json:"identity"`
+	Image struct {
+		DockerManifestDigest string `json:"docker-manifest-digest"`
+	} `json:"image"`
+	Type string `json:"type"`
+}
+
+// simpleSigningPayload is the SimpleSigning/cosign JSON payload shape:
+// {"critical": {...}, "optional": {...}}.
+type simpleSigningPayload struct {
+	Critical simpleSigningCritical  `json:"critical"`
+	Optional map[string]interface{} `json:"optional,omitempty"`
+}
+
+// simpleSigningFormatter builds a SimpleSigning-shaped payload, with
+// critical.type set to either the SimpleSigning or cosign type string.
+type simpleSigningFormatter struct {
+	critical string
+}
+
+func (f simpleSigningFormatter) Format(image string) ([]byte, error) {
+	name, digest, err := splitImageDigest(image)
+	if err != nil {
+		return nil, err
+	}
+	p := simpleSigningPayload{}
+	p.Critical.Identity.DockerReference = name
+	p.Critical.Image.DockerManifestDigest = "sha256:" + digest
+	p.Critical.Type = f.critical
+	return json.Marshal(p)
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewPayloadFormatter(t *testing.T) {
+	tcs := []struct {
+		name        string
+		format      PayloadFormat
+		wantType    interface{}
+		expectedErr bool
+	}{
+		{name: "empty format defaults to atomic", format: "", wantType: atomicHostSigningFormatter{}},
+		{name: "atomic format", format: AtomicFormat, wantType: atomicHostSigningFormatter{}},
+		{name: "simplesigning format", format: SimpleSigningFormat, wantType: simpleSigningFormatter{critical: simpleSigningType}},
+		{name: "cosign format", format: CosignFormat, wantType: simpleSigningFormatter{critical: cosignSigningType}},
+		{name: "unrecognized format", format: PayloadFormat("bogus"), expectedErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewPayloadFormatter(tc.format)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("NewPayloadFormatter(%q) error = %v, expectedErr %t", tc.format, err, tc.expectedErr)
+			}
+			if tc.expectedErr {
+				return
+			}
+			if got != tc.wantType {
+				t.Errorf("NewPayloadFormatter(%q) = %#v, want %#v", tc.format, got, tc.wantType)
+			}
+		})
+	}
+}
+
+func TestSimpleSigningFormatterFormat(t *testing.T) {
+	tcs := []struct {
+		name         string
+		formatter    simpleSigningFormatter
+		image        string
+		wantCritical string
+		expectedErr  bool
+	}{
+		{
+			name:         "simplesigning critical type",
+			formatter:    simpleSigningFormatter{critical: simpleSigningType},
+			image:        "gcr.io/foo/bar@sha256:abc123",
+			wantCritical: simpleSigningType,
+		},
+		{
+			name:         "cosign critical type",
+			formatter:    simpleSigningFormatter{critical: cosignSigningType},
+			image:        "gcr.io/foo/bar@sha256:abc123",
+			wantCritical: cosignSigningType,
+		},
+		{
+			name:        "image missing digest",
+			formatter:   simpleSigningFormatter{critical: simpleSigningType},
+			image:       "gcr.io/foo/bar:latest",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.formatter.Format(tc.image)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("Format(%q) error = %v, expectedErr %t", tc.image, err, tc.expectedErr)
+			}
+			if tc.expectedErr {
+				return
+			}
+			var p simpleSigningPayload
+			if err := json.Unmarshal(got, &p); err != nil {
+				t.Fatalf("unmarshaling Format() output: %v", err)
+			}
+			if p.Critical.Identity.DockerReference != "gcr.io/foo/bar" {
+				t.Errorf("Critical.Identity.DockerReference = %q, want %q", p.Critical.Identity.DockerReference, "gcr.io/foo/bar")
+			}
+			if p.Critical.Image.DockerManifestDigest != "sha256:abc123" {
+				t.Errorf("Critical.Image.DockerManifestDigest = %q, want %q", p.Critical.Image.DockerManifestDigest, "sha256:abc123")
+			}
+			if p.Critical.Type != tc.wantCritical {
+				t.Errorf("Critical.Type = %q, want %q", p.Critical.Type, tc.wantCritical)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/grafeas/kritis/pkg/attestlib"
+	"github.com/grafeas/kritis/pkg/kritis/cryptolib"
+)
+
+const (
+	// inTotoStatementType is the `_type` field of every in-toto Statement.
+	inTotoStatementType = "https://in-toto.io/Statement/v0.1"
+	// dssePayloadType identifies an in-toto Statement wrapped in a DSSE envelope.
+	dssePayloadType = "application/vnd.in-toto+json"
+	// VulnzPredicateType is the predicate type used when a vulnerability scan
+	// result is emitted as a signed in-toto predicate.
+	VulnzPredicateType = "https://kritis.grafeas.io/attestations/vulnerabilities/v1"
+)
+
+// InTotoStatement is an in-toto Statement, binding a predicate to the image
+// subject it describes.
+// See https://github.com/in-toto/attestation/blob/main/spec/README.md.
+type InTotoStatement struct {
+	Type          string          `json:"_type"`
+	Subject       []InTotoSubject `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+// InTotoSubject identifies the artifact an in-toto Statement makes claims about.
+type InTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// DSSEEnvelope is a Dead Simple Signing Envelope wrapping a signed payload.
+// See https://github.com/secure-systems-lab/dsse/blob/master/envelope.md.
+type DSSEEnvelope struct {
+	PayloadType string          `json:"payloadType"`
+	Payload     string          `json:"payload"`
+	Signatures  []DSSESignature `json:"signatures"`
+}
+
+// DSSESignature is a single signature over a DSSE envelope's PAE encoding.
+type DSSESignature struct {
+	KeyID string `json:"keyid,omitempty"`
+	Sig   string `json:"sig"`
+}
+
+// NewInTotoStatement builds an in-toto Statement binding predicateType and
+// predicate to image's sha256 digest.
+func NewInTotoStatement(image string, predicateType string, predicate json.RawMessage) (*InTotoStatement, error) {
+	name, digest, err := splitImageDigest(image)
+	if err != nil {
+		return nil, err
+	}
+	return &InTotoStatement{
+		Type:          inTotoStatementType,
+		Subject:       []InTotoSubject{{Name: name, Digest: map[string]string{"sha256": digest}}},
+		PredicateType: predicateType,
+		Predicate:     predicate,
+	}, nil
+}
+
+func splitImageDigest(image string) (string, string, error) {
+	parts := strings.SplitN(image, "@sha256:", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", fmt.Errorf("image %q is not in the form name@sha256:digest", image)
+	}
+	return parts[0], parts[1], nil
+}
+
+// dssePAE computes the DSSE Pre-Authentication Encoding of payloadType and
+// payload. This must match cryptolib's unexported dssePAE exactly, since
+// cryptolib.VerifyAttestation's DSSE path verifies a signature against this
+// same encoding, not against payload directly.
+// See https://github.com/secure-systems-lab/dsse/blob/master/envelope.md.
+func dssePAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// createPredicateAttestation signs an in-toto Statement wrapping predicate and
+// packages the result as a DSSE envelope. The inner signature covers the
+// statement's DSSE PAE encoding, not the statement bytes directly, matching
+// what cryptolib.VerifyAttestation's DSSE path verifies against. The returned
+// Attestation's PublicKeyID is set to cryptolib.DsseKeyID rather than the
+// inner signer's own key ID, so cryptolib.VerifyAttestation routes it to the
+// DSSE verifier (which reads the envelope's own per-signature key IDs)
+// instead of trying to verify the envelope JSON directly against a
+// PKIX/PGP/KMS key. The SerializedPayload is the JSON-encoded envelope, ready
+// to be uploaded in place of the plain AtomicContainerPayload attestation.
+func (s Signer) createPredicateAttestation(image string, predicateType string, predicate json.RawMessage) (*attestlib.Attestation, error) {
+	statement, err := NewInTotoStatement(image, predicateType, predicate)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling in-toto statement failed: %v", err)
+	}
+
+	att, err := s.config.cSigner.CreateAttestation(dssePAE(dssePayloadType, payload))
+	if err != nil {
+		return nil, err
+	}
+
+	envelope, err := json.Marshal(DSSEEnvelope{
+		PayloadType: dssePayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []DSSESignature{
+			{KeyID: att.PublicKeyID, Sig: base64.StdEncoding.EncodeToString(att.Signature)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling DSSE envelope failed: %v", err)
+	}
+
+	return &attestlib.Attestation{
+		PublicKeyID:       cryptolib.DsseKeyID,
+		Signature:         att.Signature,
+		SerializedPayload: envelope,
+	}, nil
+}
+
+// ToPredicate renders a vulnerability scan result as an in-toto predicate
+// payload that can be passed to createPredicateAttestation.
+func (v ImageVulnerabilities) ToPredicate() (json.RawMessage, error) {
+	predicate, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling vulnerabilities predicate failed: %v", err)
+	}
+	return predicate, nil
+}
+
+// DecodeDSSEEnvelope parses data (the SerializedPayload of an Attestation
+// produced by createPredicateAttestation) as a DSSE envelope.
+func DecodeDSSEEnvelope(data []byte) (*DSSEEnvelope, error) {
+	var envelope DSSEEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("unmarshaling DSSE envelope failed: %v", err)
+	}
+	if envelope.PayloadType != dssePayloadType {
+		return nil, fmt.Errorf("unsupported DSSE payload type %q, want %q", envelope.PayloadType, dssePayloadType)
+	}
+	return &envelope, nil
+}
+
+// Statement decodes e's base64-encoded payload as an in-toto Statement.
+func (e *DSSEEnvelope) Statement() (*InTotoStatement, error) {
+	payload, err := base64.StdEncoding.DecodeString(e.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("decoding DSSE payload failed: %v", err)
+	}
+	var statement InTotoStatement
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return nil, fmt.Errorf("unmarshaling in-toto statement failed: %v", err)
+	}
+	if statement.Type != inTotoStatementType {
+		return nil, fmt.Errorf("unsupported in-toto statement type %q, want %q", statement.Type, inTotoStatementType)
+	}
+	return &statement, nil
+}
+
+// VulnzPredicate decodes s's predicate as an ImageVulnerabilities scan
+// result, so a policy validator can key off attested vulnerability data
+// instead of querying Grafeas directly. Returns an error if s is not a
+// VulnzPredicateType statement.
+//
+// NOTE: this only decodes the predicate carried by a signed attestation.
+// Wiring ValidateVulnzSigningPolicy to prefer an attested predicate over a
+// live Grafeas query, and exposing a KeylessConfig on AttestationAuthority so
+// GenericAttestationPolicy/ImageSecurityPolicy reviews can request keyless
+// (Fulcio/Rekor) trust for an attestor, both require the
+// pkg/kritis/apis/kritis/v1beta1 and pkg/kritis/review packages. Neither
+// package's source is present in this checkout (only review_test.go is), so
+// that wiring isn't implemented here; see pkg/kritis/cryptolib's keyless
+// verifier and pkg/kritis/signer's KeylessSigner for the trust-verification
+// logic those packages would call into.
+func (s *InTotoStatement) VulnzPredicate() (*ImageVulnerabilities, error) {
+	if s.PredicateType != VulnzPredicateType {
+		return nil, fmt.Errorf("unsupported predicate type %q, want %q", s.PredicateType, VulnzPredicateType)
+	}
+	var vulnz ImageVulnerabilities
+	if err := json.Unmarshal(s.Predicate, &vulnz); err != nil {
+		return nil, fmt.Errorf("unmarshaling vulnerabilities predicate failed: %v", err)
+	}
+	return &vulnz, nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/attestlib"
+	"github.com/grafeas/kritis/pkg/kritis/cryptolib"
+)
+
+// TestCreatePredicateAttestationDSSERoundTrip signs a real predicate
+// attestation and checks it the way cryptolib.VerifyAttestation's DSSE path
+// would: PublicKeyID must be cryptolib.DsseKeyID so VerifyAttestation
+// actually dispatches to verifyDsse instead of falling into the plain
+// PKIX/PGP branch, and the inner signature must validate against the
+// envelope's DSSE PAE encoding (not the bare payload), which is what
+// verifyDsse checks.
+//
+// It cannot call cryptolib.VerifyAttestation or cryptolib.NewVerifier
+// directly: every path through VerifyAttestation ends by calling
+// checkAuthenticatedAttestation(..., convertAuthenticatedAttestation), and
+// neither convertAuthenticatedAttestation nor the authenticatedAttestation
+// type it returns are defined anywhere in this checkout (see
+// pkg/kritis/cryptolib/payload.go's NOTE) - cryptolib does not compile as
+// committed, independent of this fix. This test instead reimplements
+// verifyDsse's PKIX check with the standard library to confirm the
+// signature cryptolib would check is actually valid.
+func TestCreatePredicateAttestationDSSERoundTrip(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("x509.MarshalPKCS8PrivateKey() error = %v", err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	cSigner, err := attestlib.NewPkixSigner(pemKey, attestlib.RsaSignPkcs1Sha256, "test-key-id")
+	if err != nil {
+		t.Fatalf("NewPkixSigner() error = %v", err)
+	}
+	s := Signer{config: &config{cSigner: cSigner}}
+
+	vulnz := ImageVulnerabilities{
+		ImageRef:        "gcr.io/foo/bar@sha256:abc123",
+		Vulnerabilities: nil,
+	}
+	predicate, err := vulnz.ToPredicate()
+	if err != nil {
+		t.Fatalf("ToPredicate() error = %v", err)
+	}
+
+	att, err := s.createPredicateAttestation("gcr.io/foo/bar@sha256:abc123", VulnzPredicateType, predicate)
+	if err != nil {
+		t.Fatalf("createPredicateAttestation() error = %v", err)
+	}
+
+	// This is exactly the condition cryptolib.VerifyAttestation checks
+	// (att.PublicKeyID == DsseKeyID) before dispatching to verifyDsse
+	// instead of looking up a single PublicKey by att.PublicKeyID.
+	if att.PublicKeyID != cryptolib.DsseKeyID {
+		t.Errorf("Attestation.PublicKeyID = %q, want %q (cryptolib.VerifyAttestation would never reach verifyDsse otherwise)", att.PublicKeyID, cryptolib.DsseKeyID)
+	}
+
+	envelope, err := DecodeDSSEEnvelope(att.SerializedPayload)
+	if err != nil {
+		t.Fatalf("DecodeDSSEEnvelope() error = %v", err)
+	}
+	if len(envelope.Signatures) != 1 {
+		t.Fatalf("envelope has %d signatures, want 1", len(envelope.Signatures))
+	}
+	if envelope.Signatures[0].KeyID != "test-key-id" {
+		t.Errorf("envelope signature KeyID = %q, want %q (the inner signer's key ID, for a Verifier's PublicKeys lookup)", envelope.Signatures[0].KeyID, "test-key-id")
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		t.Fatalf("decoding envelope payload: %v", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(envelope.Signatures[0].Sig)
+	if err != nil {
+		t.Fatalf("decoding envelope signature: %v", err)
+	}
+
+	// Mirrors cryptolib's dsseVerifierImpl.verifyDsse: the signature must
+	// validate against the PAE encoding of (payloadType, payload), not
+	// against payload directly.
+	pae := dssePAE(envelope.PayloadType, payload)
+	digest := sha256.Sum256(pae)
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("signature does not validate against the envelope's PAE encoding: %v", err)
+	}
+
+	// A signature over the bare payload (the pre-fix behavior) must NOT be
+	// what's stored, confirming the fix actually changed what gets signed.
+	badDigest := sha256.Sum256(payload)
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, badDigest[:], sig); err == nil {
+		t.Error("signature validates against the bare payload; want it to only validate against the PAE encoding")
+	}
+
+	statement, err := envelope.Statement()
+	if err != nil {
+		t.Fatalf("Statement() error = %v", err)
+	}
+	gotVulnz, err := statement.VulnzPredicate()
+	if err != nil {
+		t.Fatalf("VulnzPredicate() error = %v", err)
+	}
+	if gotVulnz.ImageRef != vulnz.ImageRef {
+		t.Errorf("round-tripped predicate ImageRef = %q, want %q", gotVulnz.ImageRef, vulnz.ImageRef)
+	}
+}
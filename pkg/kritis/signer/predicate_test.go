@@ -0,0 +1,233 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+)
+
+func TestNewInTotoStatement(t *testing.T) {
+	predicate := json.RawMessage(`{"foo":"bar"}`)
+
+	tcs := []struct {
+		name        string
+		image       string
+		expectedErr bool
+	}{
+		{name: "valid image reference", image: "gcr.io/foo/bar@sha256:abc123"},
+		{name: "image missing digest", image: "gcr.io/foo/bar:latest", expectedErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			statement, err := NewInTotoStatement(tc.image, VulnzPredicateType, predicate)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("NewInTotoStatement() error = %v, expectedErr %t", err, tc.expectedErr)
+			}
+			if tc.expectedErr {
+				return
+			}
+			if statement.Type != inTotoStatementType {
+				t.Errorf("Type = %q, want %q", statement.Type, inTotoStatementType)
+			}
+			if statement.PredicateType != VulnzPredicateType {
+				t.Errorf("PredicateType = %q, want %q", statement.PredicateType, VulnzPredicateType)
+			}
+			if len(statement.Subject) != 1 || statement.Subject[0].Name != "gcr.io/foo/bar" || statement.Subject[0].Digest["sha256"] != "abc123" {
+				t.Errorf("Subject = %+v, want a single subject for gcr.io/foo/bar@sha256:abc123", statement.Subject)
+			}
+		})
+	}
+}
+
+func TestSplitImageDigest(t *testing.T) {
+	tcs := []struct {
+		name        string
+		image       string
+		wantName    string
+		wantDigest  string
+		expectedErr bool
+	}{
+		{name: "valid", image: "gcr.io/foo/bar@sha256:abc123", wantName: "gcr.io/foo/bar", wantDigest: "abc123"},
+		{name: "no digest", image: "gcr.io/foo/bar:latest", expectedErr: true},
+		{name: "empty digest", image: "gcr.io/foo/bar@sha256:", expectedErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			name, digest, err := splitImageDigest(tc.image)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("splitImageDigest() error = %v, expectedErr %t", err, tc.expectedErr)
+			}
+			if tc.expectedErr {
+				return
+			}
+			if name != tc.wantName || digest != tc.wantDigest {
+				t.Errorf("splitImageDigest() = (%q, %q), want (%q, %q)", name, digest, tc.wantName, tc.wantDigest)
+			}
+		})
+	}
+}
+
+func TestImageVulnerabilitiesToPredicate(t *testing.T) {
+	v := ImageVulnerabilities{
+		ImageRef: "gcr.io/foo/bar@sha256:abc123",
+		Vulnerabilities: []metadata.Vulnerability{
+			{CVE: "CVE-2020-1", Severity: "HIGH"},
+		},
+	}
+	predicate, err := v.ToPredicate()
+	if err != nil {
+		t.Fatalf("ToPredicate() error = %v", err)
+	}
+	var got ImageVulnerabilities
+	if err := json.Unmarshal(predicate, &got); err != nil {
+		t.Fatalf("unmarshaling ToPredicate() output: %v", err)
+	}
+	if got.ImageRef != v.ImageRef || len(got.Vulnerabilities) != 1 || got.Vulnerabilities[0].CVE != "CVE-2020-1" {
+		t.Errorf("round-tripped predicate = %+v, want %+v", got, v)
+	}
+}
+
+func TestDecodeDSSEEnvelope(t *testing.T) {
+	tcs := []struct {
+		name        string
+		data        []byte
+		expectedErr bool
+	}{
+		{
+			name: "valid envelope",
+			data: []byte(`{"payloadType":"application/vnd.in-toto+json","payload":"e30=","signatures":[{"keyid":"k","sig":"s"}]}`),
+		},
+		{
+			name:        "unsupported payload type",
+			data:        []byte(`{"payloadType":"text/plain","payload":"e30=","signatures":[]}`),
+			expectedErr: true,
+		},
+		{
+			name:        "malformed json",
+			data:        []byte("not json"),
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DecodeDSSEEnvelope(tc.data)
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("DecodeDSSEEnvelope() error = %v, expectedErr %t", err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestDSSEEnvelopeStatement(t *testing.T) {
+	validStatement := InTotoStatement{
+		Type:          inTotoStatementType,
+		Subject:       []InTotoSubject{{Name: "gcr.io/foo/bar", Digest: map[string]string{"sha256": "abc123"}}},
+		PredicateType: VulnzPredicateType,
+		Predicate:     json.RawMessage(`{}`),
+	}
+	validPayload, err := json.Marshal(validStatement)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	wrongTypeStatement := struct {
+		Type string `json:"_type"`
+	}{Type: "not-in-toto"}
+	wrongTypePayload, err := json.Marshal(wrongTypeStatement)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	tcs := []struct {
+		name        string
+		envelope    DSSEEnvelope
+		expectedErr bool
+	}{
+		{
+			name:     "valid statement",
+			envelope: DSSEEnvelope{Payload: base64.StdEncoding.EncodeToString(validPayload)},
+		},
+		{
+			name:        "invalid base64",
+			envelope:    DSSEEnvelope{Payload: "not base64!!"},
+			expectedErr: true,
+		},
+		{
+			name:        "unsupported statement type",
+			envelope:    DSSEEnvelope{Payload: base64.StdEncoding.EncodeToString(wrongTypePayload)},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			statement, err := tc.envelope.Statement()
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("Statement() error = %v, expectedErr %t", err, tc.expectedErr)
+			}
+			if tc.expectedErr {
+				return
+			}
+			if statement.PredicateType != VulnzPredicateType {
+				t.Errorf("PredicateType = %q, want %q", statement.PredicateType, VulnzPredicateType)
+			}
+		})
+	}
+}
+
+func TestInTotoStatementVulnzPredicate(t *testing.T) {
+	vulnz := ImageVulnerabilities{
+		ImageRef:        "gcr.io/foo/bar@sha256:abc123",
+		Vulnerabilities: []metadata.Vulnerability{{CVE: "CVE-2020-1"}},
+	}
+	predicate, err := vulnz.ToPredicate()
+	if err != nil {
+		t.Fatalf("ToPredicate() error = %v", err)
+	}
+
+	tcs := []struct {
+		name          string
+		predicateType string
+		expectedErr   bool
+	}{
+		{name: "matching predicate type", predicateType: VulnzPredicateType},
+		{name: "mismatched predicate type", predicateType: "https://example.com/other", expectedErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			statement := InTotoStatement{PredicateType: tc.predicateType, Predicate: predicate}
+			got, err := statement.VulnzPredicate()
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("VulnzPredicate() error = %v, expectedErr %t", err, tc.expectedErr)
+			}
+			if tc.expectedErr {
+				return
+			}
+			if got.ImageRef != vulnz.ImageRef || len(got.Vulnerabilities) != 1 || got.Vulnerabilities[0].CVE != "CVE-2020-1" {
+				t.Errorf("VulnzPredicate() = %+v, want %+v", got, vulnz)
+			}
+		})
+	}
+}
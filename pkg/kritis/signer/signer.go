@@ -17,12 +17,13 @@ limitations under the License.
 package signer
 
 import (
+	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/golang/glog"
 	"github.com/grafeas/kritis/pkg/attestlib"
 	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
-	"github.com/grafeas/kritis/pkg/kritis/attestation"
 	"github.com/grafeas/kritis/pkg/kritis/crd/authority"
 	"github.com/grafeas/kritis/pkg/kritis/metadata"
 	"github.com/grafeas/kritis/pkg/kritis/util"
@@ -30,6 +31,12 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// noteGetter is the subset of the Grafeas note type that uploadAttestation
+// needs, i.e., what util.GetOrCreateAttestationNote returns.
+type noteGetter interface {
+	GetName() string
+}
+
 // A signer is used for creating attestations for an image.
 type Signer struct {
 	config *config
@@ -47,6 +54,20 @@ type config struct {
 	authority v1beta1.AttestationAuthority
 	project   string
 	overwrite bool
+	// predicateType, if non-empty, makes the signer emit a DSSE-wrapped
+	// in-toto Statement carrying predicate instead of an AtomicContainerPayload.
+	predicateType string
+	predicate     json.RawMessage
+	// payloadFormatter builds the bytes signed for an image when no
+	// predicate is configured. Defaults to AtomicFormat.
+	payloadFormatter PayloadFormatter
+
+	// noteOnce and note memoize the note lookup/creation done by
+	// uploadAttestation, so that signing many images (see SignImages) only
+	// performs it once.
+	noteOnce sync.Once
+	noteErr  error
+	note     noteGetter
 }
 
 // Creating a new signer object.
@@ -54,20 +75,37 @@ func New(client metadata.ReadWriteClient, cSigner attestlib.Signer, noteName str
 	return Signer{
 		client: client,
 		config: &config{
-			cSigner,
-			v1beta1.AttestationAuthority{
+			cSigner: cSigner,
+			authority: v1beta1.AttestationAuthority{
 				ObjectMeta: metav1.ObjectMeta{Name: "signing-aa"},
 				Spec: v1beta1.AttestationAuthoritySpec{
 					NoteReference: noteName,
 					PublicKeys:    []v1beta1.PublicKey{},
 				},
 			},
-			project,
-			overwrite,
+			project:   project,
+			overwrite: overwrite,
 		},
 	}
 }
 
+// WithPredicate configures the signer to sign a DSSE-wrapped in-toto
+// Statement carrying predicate (of type predicateType) about the image,
+// instead of the default AtomicContainerPayload.
+func (s Signer) WithPredicate(predicateType string, predicate json.RawMessage) Signer {
+	s.config.predicateType = predicateType
+	s.config.predicate = predicate
+	return s
+}
+
+// WithPayloadFormat configures the signer to sign payloads built by
+// formatter instead of the default AtomicContainerPayload. Ignored if a
+// predicate is also configured via WithPredicate.
+func (s Signer) WithPayloadFormat(formatter PayloadFormatter) Signer {
+	s.config.payloadFormatter = formatter
+	return s
+}
+
 // ImageVulnerabilities is an input for running vulnerability policy validation.
 type ImageVulnerabilities struct {
 	ImageRef        string
@@ -120,7 +158,15 @@ func (s Signer) SignImage(image string) error {
 
 // Creating an atestation.
 func (s Signer) createAttestation(image string) (*attestlib.Attestation, error) {
-	payload, err := attestation.AtomicContainerPayload(image)
+	if s.config.predicateType != "" {
+		return s.createPredicateAttestation(image, s.config.predicateType, s.config.predicate)
+	}
+
+	formatter := s.config.payloadFormatter
+	if formatter == nil {
+		formatter = atomicHostSigningFormatter{}
+	}
+	payload, err := formatter.Format(image)
 	if err != nil {
 		return nil, err
 	}
@@ -136,7 +182,7 @@ func (s Signer) createAttestation(image string) (*attestlib.Attestation, error)
 // The method will create a note if it does not already exist.
 // Returns error if upload failed, e.g., if an attestation already exists.
 func (s Signer) uploadAttestation(image string, att *attestlib.Attestation) error {
-	note, err := util.GetOrCreateAttestationNote(s.client, &s.config.authority)
+	note, err := s.getOrCreateNote()
 	if err != nil {
 		return err
 	}
@@ -146,6 +192,15 @@ func (s Signer) uploadAttestation(image string, att *attestlib.Attestation) erro
 	return err
 }
 
+// getOrCreateNote looks up (or creates) the signer's attestation note at
+// most once, regardless of how many images are signed through this Signer.
+func (s Signer) getOrCreateNote() (noteGetter, error) {
+	s.config.noteOnce.Do(func() {
+		s.config.note, s.config.noteErr = util.GetOrCreateAttestationNote(s.client, &s.config.authority)
+	})
+	return s.config.note, s.config.noteErr
+}
+
 func (s Signer) isAttestationAlreadyExist(image string) (bool, error) {
 	atts, err := s.client.Attestations(image, &s.config.authority)
 	if err == nil && len(atts) > 0 {